@@ -0,0 +1,160 @@
+#!/usr/bin/env python3
+"""
+并行分析性能测试
+
+生成一个包含几千个 Go 文件的合成项目，分别用标准模式和性能优化模式
+（多进程）分析，输出耗时和加速比，并检查两种模式的结果完全一致。
+
+使用方法:
+    python benchmark.py
+    python benchmark.py --files 4000 --workers 8
+"""
+
+import argparse
+import os
+import shutil
+import sys
+import tempfile
+import time
+from contextlib import redirect_stdout
+
+# 将当前目录添加到 Python 路径
+sys.path.insert(0, os.path.dirname(os.path.abspath(__file__)))
+
+from call_graph.analyzer import CallGraphAnalyzer
+from call_graph.analyzer_optimized import CallGraphAnalyzerOptimized
+
+FILE_TEMPLATE = """package pkg{pkg}
+
+import "fmt"
+
+type Worker{idx} struct {{
+	Name string
+}}
+
+func (w *Worker{idx}) Run(n int) int {{
+	return helper{idx}(n) + w.step(n)
+}}
+
+func (w *Worker{idx}) step(n int) int {{
+	if n <= 0 {{
+		return 0
+	}}
+	return w.step(n - 1)
+}}
+
+func helper{idx}(n int) int {{
+	fmt.Println("helper", n)
+	return {next_call}
+}}
+
+func Entry{idx}() {{
+	w := &Worker{idx}{{Name: "w"}}
+	w.Run(3)
+	go func() {{
+		helper{idx}(1)
+	}}()
+}}
+"""
+
+
+def generate_project(root: str, num_files: int, files_per_package: int = 50):
+    """生成合成的 Go 项目，每个文件调用同一个包中下一个文件的函数"""
+    with open(os.path.join(root, "go.mod"), "w", encoding="utf-8") as f:
+        f.write("module example.com/bench\n\ngo 1.21\n")
+
+    for i in range(num_files):
+        pkg = i // files_per_package
+        package_dir = os.path.join(root, f"pkg{pkg}")
+        os.makedirs(package_dir, exist_ok=True)
+
+        last_in_package = (i + 1) % files_per_package == 0 or i + 1 == num_files
+        next_call = "n" if last_in_package else f"helper{i + 1}(n)"
+        with open(os.path.join(package_dir, f"file{i}.go"), "w", encoding="utf-8") as f:
+            f.write(FILE_TEMPLATE.format(pkg=pkg, idx=i, next_call=next_call))
+
+
+def snapshot(db):
+    """数据库内容的快照（按插入顺序），用于对比两次分析的结果"""
+    cursor = db.conn.cursor()
+    cursor.execute("SELECT id, name, file, start_line FROM symbols ORDER BY rowid")
+    symbols = [tuple(row) for row in cursor.fetchall()]
+    cursor.execute(
+        """
+        SELECT caller_id, callee_id, call_site_line, call_site_column, kind
+        FROM call_relations ORDER BY id
+    """
+    )
+    relations = [tuple(row) for row in cursor.fetchall()]
+    return symbols, relations
+
+
+def run(label, analyzer, project, **kwargs):
+    with open(os.devnull, "w") as devnull, redirect_stdout(devnull):
+        analyzer.db.clear_all()
+        start = time.time()
+        analyzer.analyze_project(project, **kwargs)
+        elapsed = time.time() - start
+    result = snapshot(analyzer.db)
+    analyzer.close()
+    print(f"{label:12s}: {elapsed:8.2f} 秒")
+    return elapsed, result
+
+
+def main():
+    parser = argparse.ArgumentParser(description="并行分析性能测试")
+    parser.add_argument(
+        "--files", type=int, default=2000, help="生成的文件数量 (默认: 2000)"
+    )
+    parser.add_argument("--workers", type=int, help="工作进程数量 (默认: CPU核心数-1)")
+    parser.add_argument("--keep", action="store_true", help="保留生成的项目和数据库")
+    args = parser.parse_args()
+
+    work_dir = tempfile.mkdtemp(prefix="call_graph_bench_")
+    project = os.path.join(work_dir, "project")
+    os.makedirs(project)
+
+    try:
+        print(f"生成 {args.files} 个文件: {project}")
+        generate_project(project, args.files)
+
+        serial_time, serial_result = run(
+            "标准模式",
+            CallGraphAnalyzer(os.path.join(work_dir, "serial.db")),
+            project,
+        )
+        optimized = CallGraphAnalyzerOptimized(
+            os.path.join(work_dir, "parallel.db"), num_workers=args.workers
+        )
+        workers = optimized.num_workers
+        parallel_time, parallel_result = run(
+            f"并行({workers}进程)", optimized, project, show_progress=False
+        )
+        _, repeat_result = run(
+            "并行(重复)",
+            CallGraphAnalyzerOptimized(
+                os.path.join(work_dir, "repeat.db"), num_workers=args.workers
+            ),
+            project,
+            show_progress=False,
+        )
+
+        print(f"\n加速比: {serial_time / parallel_time:.2f}x")
+        print(
+            f"符号数: {len(serial_result[0])}, 调用关系: {len(serial_result[1])}"
+        )
+
+        if serial_result == parallel_result == repeat_result:
+            print("结果一致: 标准模式与并行模式（两次运行）的符号和调用关系顺序完全相同")
+        else:
+            print("错误: 分析结果不一致")
+            sys.exit(1)
+    finally:
+        if args.keep:
+            print(f"\n已保留: {work_dir}")
+        else:
+            shutil.rmtree(work_dir, ignore_errors=True)
+
+
+if __name__ == "__main__":
+    main()
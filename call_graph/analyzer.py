@@ -4,74 +4,508 @@
 """
 
 import os
+import threading
+from dataclasses import replace
 from pathlib import Path
-from typing import Any, Dict, List, Optional
+from typing import Any, Dict, List, Optional, Set, Tuple
 
 # 支持相对导入和直接运行
 try:
+    from .cancel import CancelToken
     from .database import CallGraphDB
+    from .exporters import EXPORTERS
+    from .go_build import BuildContext
+    from .go_packages import list_go_packages, package_paths
+    from .go_resolver import GoIndex
+    from .graph import CallGraph, ExternalMode
+    from .options import AnalysisOptions, ExportOptions
     from .parsers import LANGUAGE_CONFIG, detect_language, get_parser
 except ImportError:
+    from cancel import CancelToken
     from database import CallGraphDB
+    from exporters import EXPORTERS
+    from go_build import BuildContext
+    from go_packages import list_go_packages, package_paths
+    from go_resolver import GoIndex
+    from graph import CallGraph, ExternalMode
+    from options import AnalysisOptions, ExportOptions
     from parsers import LANGUAGE_CONFIG, detect_language, get_parser
 
+# 默认排除的目录（隐藏目录总是被排除）
+DEFAULT_EXCLUDE_DIRS = [
+    "node_modules",
+    ".git",
+    "__pycache__",
+    "venv",
+    "env",
+    "build",
+    "dist",
+    "target",
+    ".idea",
+    ".vscode",
+    "bin",
+    "obj",
+    "vendor",
+]
+
+
+def collect_source_files(
+    project_path: Path,
+    exclude_dirs: List[str],
+    recursive: bool = True,
+    include_tests: bool = False,
+    build_context: Optional[BuildContext] = None,
+) -> List[str]:
+    """
+    收集所有源代码文件
+
+    Args:
+        project_path: 项目根目录
+        exclude_dirs: 排除的目录名列表
+        recursive: 是否递归进入子目录，为 False 时只收集根目录下的文件
+        include_tests: 是否包含 Go 的测试文件（*_test.go）
+        build_context: Go 的构建环境，指定时跳过不满足构建约束的 Go 文件
+    """
+    source_files = []
+
+    # 收集所有支持的扩展名
+    supported_extensions = set()
+    for config in LANGUAGE_CONFIG.values():
+        supported_extensions.update(config["extensions"])
+
+    for root, dirs, files in os.walk(project_path):
+        # 排除指定目录
+        if recursive:
+            dirs[:] = [
+                d for d in dirs if d not in exclude_dirs and not d.startswith(".")
+            ]
+        else:
+            dirs[:] = []
+
+        for file in sorted(files):
+            if file.endswith("_test.go") and not include_tests:
+                continue
+            if any(file.endswith(ext) for ext in supported_extensions):
+                file_path = os.path.join(root, file)
+                if (
+                    build_context is not None
+                    and file.endswith(".go")
+                    and not build_context.matches_file(file_path)
+                ):
+                    continue
+                source_files.append(file_path)
+
+    return source_files
+
+
+def list_package_files(
+    analyzer, patterns: List[str], directory: str
+) -> Tuple[List[str], AnalysisOptions]:
+    """
+    analyze_packages() 的准备工作（两种分析器共用）：用 go list 展开包模式，
+    返回 (要分析的文件, 带有 go list 导入路径的分析选项)，加载失败的包记录到
+    analyzer.errors
+    """
+    packages = list_go_packages(patterns, directory, analyzer.options)
+    source_files = set()
+    for package in packages:
+        if package.error:
+            analyzer.errors.append(
+                {
+                    "file": package.directory or package.import_path,
+                    "stage": "packages",
+                    "error": package.error,
+                }
+            )
+        source_files.update(package.files)
+    options = replace(analyzer.options, go_package_paths=package_paths(packages))
+    return sorted(source_files), options
+
+
+def record_syntax_error(errors: List[Dict[str, Any]], file_path: str, parser):
+    """
+    提取函数定义后记录文件中的语法错误（stage 为 parse）：
+    文件没有被跳过，仍然使用部分正确的语法树中提取到的结果
+    """
+    if parser.syntax_error:
+        errors.append(
+            {"file": file_path, "stage": "parse", "error": parser.syntax_error}
+        )
+
+
+def print_errors(errors: List[Dict[str, Any]]):
+    """打印分析过程中汇总的文件错误"""
+    if not errors:
+        return
+    print(
+        f"\n警告: {len(errors)} 个文件处理出错"
+        "（失败的文件已跳过，语法错误只影响出错的部分，其余结果已保存）:"
+    )
+    for error in errors:
+        print(f"  [{error['stage']}] {error['file']}: {error['error']}")
+
+
+def load_call_graph(
+    db: CallGraphDB,
+    external: str = ExternalMode.DROP,
+    merge_names: bool = False,
+    implements: bool = False,
+) -> CallGraph:
+    """
+    从数据库加载内存调用图（两种分析器的 load_graph() 共用）
+
+    implements 为 True 时，从数据库中的 Go 符号重新建立索引，
+    为项目中每个满足接口的具体类型添加一条 类型 -> 接口 的 implements 边，
+    用来解释接口调用为什么会展开到这些实现
+    """
+    graph = CallGraph.from_db(db, external, merge_names)
+    if implements:
+        index = GoIndex(db.get_symbols_by_language("go"))
+        graph.add_implements_edges(index.implements_pairs())
+    return graph
+
+
+def prepare_export_graph(
+    graph: CallGraph,
+    entry: Optional[str] = None,
+    max_depth: int = 0,
+    by_package: bool = False,
+    self_edges: bool = False,
+    filter_pattern: Optional[str] = None,
+    include_neighbors: bool = False,
+    leaf_prefixes: Optional[List[str]] = None,
+    transparent_funcs: Optional[List[str]] = None,
+) -> CallGraph:
+    """
+    按导出参数裁剪调用图（export_call_graph() 和监视模式的导出共用），
+    原图不会被修改
+
+    依次省略包装函数、截断叶子节点、从入口出发裁剪、按正则表达式过滤，
+    最后（需要时）合并为包级别的调用图。
+
+    Args:
+        graph: 从数据库加载的调用图，见 load_call_graph()
+        entry: 只保留从该函数出发可以到达的部分
+        max_depth: 与 entry 一起使用，限制距离入口的调用层数（0 表示不限制）
+        by_package: 合并为包级别的调用图（见 CallGraph.collapse_by_package）
+        self_edges: 与 by_package 一起使用，保留包内部的调用
+        filter_pattern: 只保留限定名匹配该正则表达式的函数
+            （见 CallGraph.filter_by_regexp）
+        include_neighbors: 与 filter_pattern 一起使用，保留匹配函数的直接调用者
+            和被调用者
+        leaf_prefixes: 限定名以这些前缀开头的函数作为叶子节点，不保留它们的
+            调用（见 CallGraph.with_leaves）
+        transparent_funcs: 省略的包装函数，调用者直接连到它们调用的函数
+            （见 CallGraph.elide）
+    """
+    if transparent_funcs:
+        graph = graph.elide(transparent_funcs)
+    if leaf_prefixes:
+        graph = graph.with_leaves(leaf_prefixes)
+    if entry:
+        graph = graph.reachable(entry, max_depth)
+    if filter_pattern is not None:
+        graph = graph.filter_by_regexp(filter_pattern, include_neighbors)
+    if by_package:
+        graph = graph.collapse_by_package(self_edges)
+    return graph
+
+
+def export_call_graph(
+    analyzer,
+    output_format: str = "dot",
+    external: str = ExternalMode.DROP,
+    entry: Optional[str] = None,
+    max_depth: int = 0,
+    export_options: Optional[ExportOptions] = None,
+    by_package: bool = False,
+    self_edges: bool = False,
+    merge_names: bool = False,
+    implements: bool = False,
+    filter_pattern: Optional[str] = None,
+    include_neighbors: bool = False,
+    leaf_prefixes: Optional[List[str]] = None,
+    transparent_funcs: Optional[List[str]] = None,
+) -> str:
+    """
+    加载调用图，按导出参数裁剪后导出（两种分析器的 export_graph() 共用）
+
+    裁剪相关的参数（entry、max_depth、by_package、self_edges、filter_pattern、
+    include_neighbors、leaf_prefixes、transparent_funcs）见 prepare_export_graph()。
+
+    Args:
+        analyzer: 提供 load_graph() 的分析器
+        output_format: 导出格式，见 EXPORTERS
+        external: 外部调用的处理方式，见 ExternalMode
+        export_options: 导出格式相关的配置
+        merge_names: 把不同包中的同名函数合并为一个节点
+        implements: 添加具体类型到它满足的接口的 implements 边（Go）
+    """
+    exporter = EXPORTERS.get(output_format)
+    if exporter is None:
+        raise ValueError(f"不支持的导出格式: {output_format}")
+
+    graph = prepare_export_graph(
+        analyzer.load_graph(external, merge_names, implements),
+        entry,
+        max_depth,
+        by_package,
+        self_edges,
+        filter_pattern,
+        include_neighbors,
+        leaf_prefixes,
+        transparent_funcs,
+    )
+    result = exporter(graph, export_options)
+    print(f"导出成功: {len(graph.nodes)} 个节点, {len(graph.edges)} 条边")
+    return result
+
+
+def check_resolution(analyzer):
+    """
+    分析结束后的严格模式检查（两种分析器共用）：options.strict_resolution 为 True
+    且有无法确定目标的调用时抛出 UnresolvedCallsError，见 CallGraph.check_resolved()
+    """
+    if analyzer.options.strict_resolution:
+        load_call_graph(analyzer.db).check_resolved()
+
+
+def _short_name(name: str) -> str:
+    """User.Greet、fmt.Println -> Greet、Println，用于匹配可能受影响的调用"""
+    return name.rsplit(".", 1)[-1]
+
+
+def _declared_names(symbols: List[Dict[str, Any]]) -> Set[str]:
+    """
+    符号声明的名称，以及其中被赋值的函数类型字段和赋值的函数名：
+    调用名称（最后一段）在其中的调用，解析结果可能因为这些符号的变化而改变
+    """
+    names = set()
+    for symbol in symbols:
+        names.add(_short_name(symbol["name"]))
+        for _, _, field_name, value in symbol.get("extras", {}).get(
+            "field_assigns", []
+        ):
+            names.add(field_name)
+            if value and value[0] in ("func", "method"):
+                names.add(value[-1])
+    return names
+
+
+def update_files(analyzer, changed_files: List[str]) -> Dict[str, Any]:
+    """
+    增量更新（两种分析器的 update() 共用）
+
+    删除 changed_files 中的符号和调用关系，只重新提取这些文件中的函数定义
+    （已删除或不再参与构建的文件只删除），然后重新提取调用关系的文件包括：
+    changed_files 本身；调用了这些文件中（修改前或修改后）声明的函数的文件，
+    被调用的函数移动了位置、改名或删除后调用边会重新连接；以及 Go 中与它们
+    属于同一个包的文件（包内的函数和方法可以直接互相引用）。识别受影响的文件
+    基于调用的名称，新增的方法让其他包中的接口调用多出实现等情况可能发现不了，
+    需要完全准确的结果时重新分析整个项目。
+    """
+    options = analyzer.options
+    build_context = BuildContext.from_options(options)
+    db = analyzer.db
+
+    def participates(file_path: str) -> bool:
+        if not os.path.isfile(file_path) or not detect_language(file_path):
+            return False
+        if file_path.endswith(".go"):
+            if file_path.endswith("_test.go") and not options.include_tests:
+                return False
+            return build_context.matches_file(file_path)
+        return True
+
+    changed = {str(Path(file_path).resolve()) for file_path in changed_files}
+    analyzer.errors = []
+    old_symbols = db.get_all_symbols()
+    removed = [s for s in old_symbols if s["file"] in changed]
+
+    # 第一遍：只处理变化的文件
+    parsed = sorted(f for f in changed if participates(f))
+    added: List[Dict[str, Any]] = []
+    for file_path in parsed:
+        try:
+            parser = get_parser(detect_language(file_path), options)
+            added.extend(parser.extract_functions(file_path))
+            record_syntax_error(analyzer.errors, file_path, parser)
+        except Exception as e:
+            analyzer.errors.append(
+                {"file": file_path, "stage": "functions", "error": str(e)}
+            )
+
+    names = _declared_names(removed + added)
+    packages = {
+        s["package"]
+        for s in removed + added
+        if s.get("language") == "go" and s.get("package")
+    }
+    dependents = {
+        relation["caller_file"]
+        for relation in db.get_call_relations()
+        if relation["callee_file"] in changed
+        or _short_name(relation["callee_name"] or "") in names
+    }
+    dependents.update(
+        s["file"]
+        for s in old_symbols
+        if s.get("language") == "go" and s.get("package") in packages
+    )
+    dependents -= changed
+    reparsed = sorted(set(parsed) | dependents)
+
+    kept = [s for s in old_symbols if s["file"] not in changed]
+    analyzer.all_functions = kept + added
+    db.delete_symbols_in_files(sorted(changed))
+    db.delete_calls_in_files(sorted(changed | dependents))
+    for symbol in added:
+        db.insert_symbol(symbol)
+
+    # 第二遍：变化的文件和受影响的文件
+    for file_path in reparsed:
+        try:
+            parser = get_parser(detect_language(file_path), options)
+            for call in parser.extract_calls(file_path, analyzer.all_functions):
+                db.insert_call_relation(call)
+        except Exception as e:
+            analyzer.errors.append(
+                {"file": file_path, "stage": "calls", "error": str(e)}
+            )
+
+    print(
+        f"增量分析: {len(changed)} 个文件变化，"
+        f"重新提取了 {len(reparsed)} 个文件的调用关系"
+    )
+    print_errors(analyzer.errors)
+    check_resolution(analyzer)
+    stats = db.get_statistics()
+    stats["updated_files"] = reparsed
+    stats["errors"] = analyzer.errors
+    return stats
+
 
 class CallGraphAnalyzer:
     """调用关系分析器"""
 
-    def __init__(self, db_path: str = "call_graph.db"):
+    def __init__(
+        self, db_path: str = "call_graph.db", options: Optional[AnalysisOptions] = None
+    ):
         self.db = CallGraphDB(db_path)
+        self.options = options or AnalysisOptions()
         self.all_functions: List[Dict[str, Any]] = []
+        # 处理失败的文件，分析结束后汇总到统计结果中
+        self.errors: List[Dict[str, Any]] = []
+        # update() 和 load_graph() 持有的锁：在其他线程中加载调用图时
+        # 不会读到增量更新进行到一半的数据库
+        self.lock = threading.RLock()
 
     def analyze_project(
-        self, project_path: str, exclude_dirs: Optional[List[str]] = None
+        self,
+        project_path: str,
+        exclude_dirs: Optional[List[str]] = None,
+        recursive: bool = True,
+        cancel: Optional[CancelToken] = None,
     ) -> Dict[str, Any]:
-        """分析整个项目"""
+        """
+        分析整个项目
+
+        某些文件解析失败时不会中断分析：失败的文件会被跳过，
+        其余文件的结果照常保存，失败列表通过返回值的 errors 字段汇总。
+        options.strict_resolution 为 True 时，存在无法确定目标的调用会在分析
+        结束后抛出 UnresolvedCallsError。
+
+        所有文件处理完后才在一个事务中写入数据库。cancel 被取消或超时时，
+        在处理下一个文件之前（或写入过程中）抛出 AnalysisCancelled，
+        数据库保持分析之前的状态。
+
+        Args:
+            project_path: 项目路径
+            exclude_dirs: 排除的目录列表（默认: DEFAULT_EXCLUDE_DIRS）
+            recursive: 是否递归分析子目录
+            cancel: 取消令牌，见 CancelToken
+        """
         if exclude_dirs is None:
-            exclude_dirs = [
-                "node_modules",
-                ".git",
-                "__pycache__",
-                "venv",
-                "env",
-                "build",
-                "dist",
-                "target",
-                ".idea",
-                ".vscode",
-                "bin",
-                "obj",
-            ]
+            exclude_dirs = DEFAULT_EXCLUDE_DIRS
 
         project_path = Path(project_path).resolve()
 
         print(f"开始分析项目: {project_path}")
 
+        self.errors = []
+        self.all_functions = []
+
         # 收集所有源代码文件
-        source_files = self._collect_source_files(project_path, exclude_dirs)
+        source_files = collect_source_files(
+            project_path,
+            exclude_dirs,
+            recursive,
+            self.options.include_tests,
+            BuildContext.from_options(self.options),
+        )
+        return self._analyze_files(source_files, cancel)
+
+    def analyze_packages(
+        self,
+        patterns: List[str],
+        directory: str = ".",
+        cancel: Optional[CancelToken] = None,
+    ) -> Dict[str, Any]:
+        """
+        按 Go 包模式分析，如 ./...、example.com/foo/...（需要安装 Go 工具链）
+
+        包模式由 go list 在 directory 中展开，模块解析、vendor 和构建约束
+        （options 中的 goos/goarch/build_tags）都由 Go 工具链处理，包的导入路径
+        使用 go list 的结果。调用解析与 analyze_project() 相同，基于语法树推断类型。
+        加载失败的包记录在返回值的 errors 中；没有 go 命令时抛出 RuntimeError，
+        可以改用 analyze_project() 按目录分析。取消的规则见 analyze_project()，
+        go list 本身不会被中断，结束后才检查 cancel。
+        """
+        print(f"开始分析 Go 包: {' '.join(patterns)}")
+        self.errors = []
+        self.all_functions = []
+
+        source_files, options = list_package_files(self, patterns, directory)
+        original = self.options
+        self.options = options
+        try:
+            return self._analyze_files(source_files, cancel)
+        finally:
+            self.options = original
 
+    def _analyze_files(
+        self, source_files: List[str], cancel: Optional[CancelToken] = None
+    ) -> Dict[str, Any]:
+        """分析收集到的源文件（两遍扫描）并返回统计结果"""
+        cancel = cancel or CancelToken()
         print(f"找到 {len(source_files)} 个源代码文件")
 
         # 第一遍：提取所有函数定义
         print("第一遍扫描：提取函数定义...")
         for file_path in source_files:
+            cancel.check()
             self._extract_functions_from_file(file_path)
 
         print(f"共提取 {len(self.all_functions)} 个函数定义")
 
-        # 保存函数定义到数据库
-        print("保存函数定义到数据库...")
-        for func in self.all_functions:
-            self.db.insert_symbol(func)
-
-        # 第二遍：提取调用关系
+        # 第二遍：提取调用关系（只依赖内存中的函数定义，不读取数据库）
         print("第二遍扫描：提取调用关系...")
-        total_calls = 0
+        all_calls = []
         for file_path in source_files:
-            calls = self._extract_calls_from_file(file_path)
-            total_calls += len(calls)
-
-        print(f"共提取 {total_calls} 个调用关系")
+            cancel.check()
+            all_calls.extend(self._extract_calls_from_file(file_path))
+
+        print(f"共提取 {len(all_calls)} 个调用关系")
+
+        # 在一个事务中保存，取消时回滚；持有锁，其他线程不会读到一半的结果
+        print("保存到数据库...")
+        with self.lock, self.db.transaction():
+            for func in self.all_functions:
+                cancel.check()
+                self.db.insert_symbol(func)
+            for call in all_calls:
+                cancel.check()
+                self.db.insert_call_relation(call)
 
         # 生成统计报告
         stats = self.db.get_statistics()
@@ -83,31 +517,11 @@ class CallGraphAnalyzer:
         for lang, count in stats["by_language"].items():
             print(f"  {lang}: {count}")
 
-        return stats
-
-    def _collect_source_files(
-        self, project_path: Path, exclude_dirs: List[str]
-    ) -> List[str]:
-        """收集所有源代码文件"""
-        source_files = []
-
-        # 收集所有支持的扩展名
-        supported_extensions = set()
-        for config in LANGUAGE_CONFIG.values():
-            supported_extensions.update(config["extensions"])
-
-        for root, dirs, files in os.walk(project_path):
-            # 排除指定目录
-            dirs[:] = [
-                d for d in dirs if d not in exclude_dirs and not d.startswith(".")
-            ]
-
-            for file in files:
-                if any(file.endswith(ext) for ext in supported_extensions):
-                    file_path = os.path.join(root, file)
-                    source_files.append(file_path)
+        print_errors(self.errors)
+        check_resolution(self)
 
-        return source_files
+        stats["errors"] = self.errors
+        return stats
 
     def _extract_functions_from_file(self, file_path: str):
         """从文件中提取函数定义"""
@@ -116,31 +530,41 @@ class CallGraphAnalyzer:
             return
 
         try:
-            parser = get_parser(language)
+            parser = get_parser(language, self.options)
             functions = parser.extract_functions(file_path)
             self.all_functions.extend(functions)
+            record_syntax_error(self.errors, file_path, parser)
         except Exception as e:
             print(f"警告: 提取函数失败 {file_path}: {e}")
+            self.errors.append(
+                {"file": file_path, "stage": "functions", "error": str(e)}
+            )
 
     def _extract_calls_from_file(self, file_path: str) -> List[Dict[str, Any]]:
-        """从文件中提取调用关系"""
+        """从文件中提取调用关系（由调用方保存到数据库）"""
         language = detect_language(file_path)
         if not language:
             return []
 
         try:
-            parser = get_parser(language)
-            calls = parser.extract_calls(file_path, self.all_functions)
-
-            # 保存到数据库
-            for call in calls:
-                self.db.insert_call_relation(call)
-
-            return calls
+            parser = get_parser(language, self.options)
+            return parser.extract_calls(file_path, self.all_functions)
         except Exception as e:
             print(f"警告: 提取调用关系失败 {file_path}: {e}")
+            self.errors.append(
+                {"file": file_path, "stage": "calls", "error": str(e)}
+            )
             return []
 
+    def update(self, changed_files: List[str]) -> Dict[str, Any]:
+        """
+        文件变化后增量更新数据库，只重新解析变化的文件和受影响的文件，
+        规则见 update_files()。返回统计结果，updated_files 为重新提取了调用关系的
+        文件。之前通过 load_graph() 得到的调用图不会被修改，需要时重新加载。
+        """
+        with self.lock:
+            return update_files(self, changed_files)
+
     def analyze_file(self, file_path: str) -> Dict[str, Any]:
         """分析单个文件"""
         language = detect_language(file_path)
@@ -151,7 +575,7 @@ class CallGraphAnalyzer:
                 "calls": [],
             }
 
-        parser = get_parser(language)
+        parser = get_parser(language, self.options)
 
         # 提取函数定义
         functions = parser.extract_functions(file_path)
@@ -182,12 +606,18 @@ class CallGraphAnalyzer:
         """查询指定函数调用的所有函数"""
         return self.db.get_callees(function_name)
 
-    def query_call_chain(self, function_name: str, depth: int = 5) -> List[List[str]]:
-        """查询函数的调用链（向下）"""
-        return self.db.get_call_chain(function_name, depth)
+    def query_call_chain(
+        self, function_name: str, depth: int = 5, merge_names: bool = False
+    ) -> List[List[str]]:
+        """查询函数的调用链（向下），merge_names 见 CallGraphDB.get_call_chain"""
+        return self.db.get_call_chain(function_name, depth, merge_names)
 
     def query_full_call_paths(
-        self, function_name: str, max_depth: int = 10, max_paths: int = 1000
+        self,
+        function_name: str,
+        max_depth: int = 10,
+        max_paths: int = 1000,
+        merge_names: bool = False,
     ) -> Dict[str, Any]:
         """
         查询函数的完整调用路径（向上+向下）
@@ -196,8 +626,11 @@ class CallGraphAnalyzer:
             function_name: 目标函数名称
             max_depth: 最大搜索深度
             max_paths: 最大路径数量限制（性能优化）
+            merge_names: 把不同包中的同名函数当作同一个函数
         """
-        return self.db.get_full_call_paths(function_name, max_depth, max_paths)
+        return self.db.get_full_call_paths(
+            function_name, max_depth, max_paths, merge_names
+        )
 
     def search_functions(self, pattern: str) -> List[Dict[str, Any]]:
         """搜索函数"""
@@ -207,59 +640,56 @@ class CallGraphAnalyzer:
         """获取统计信息"""
         return self.db.get_statistics()
 
-    def export_graph(self, output_format: str = "dot") -> str:
-        """导出调用图"""
-        if output_format == "dot":
-            return self._export_dot()
-        else:
-            raise ValueError(f"不支持的导出格式: {output_format}")
-
-    def _export_dot(self) -> str:
-        """导出为Graphviz DOT格式"""
-        lines = ["digraph CallGraph {"]
-        lines.append("  rankdir=LR;")
-        lines.append("  node [shape=box];")
-        lines.append('  graph [fontname="Arial", fontsize=10];')
-        lines.append('  node [fontname="Arial", fontsize=9];')
-        lines.append('  edge [fontname="Arial", fontsize=8];')
-
-        # 添加所有函数节点
-        symbols = self.db.get_symbols_by_kind("function")
-        valid_node_ids = set()
-        for symbol in symbols:
-            node_id = symbol["id"]
-            valid_node_ids.add(node_id)
-            # 转义特殊字符
-            name = symbol["name"].replace('"', '\\"')
-            file_path = symbol["file"].replace('"', '\\"')
-            label = f"{name}\\n({file_path}:{symbol.get('start_line', '?')})"
-            lines.append(f'  "{node_id}" [label="{label}"];')
-
-        # 添加调用边（只添加有效的边）
-        cursor = self.db.conn.cursor()
-        cursor.execute(
-            """
-            SELECT caller_id, callee_id, caller_name, callee_name 
-            FROM call_relations 
-            WHERE caller_id IS NOT NULL AND callee_id IS NOT NULL
+    def load_graph(
+        self,
+        external: str = ExternalMode.DROP,
+        merge_names: bool = False,
+        implements: bool = False,
+    ) -> CallGraph:
+        """
+        把数据库中的函数和调用关系加载为内存调用图（适合大量重复查询）
+
+        Args:
+            external: 外部调用的处理方式，见 ExternalMode（默认丢弃）
+            merge_names: 把不同包中的同名函数合并为一个节点（默认按限定名区分）
+            implements: 添加 Go 的类型节点和 implements 边，见 load_call_graph()
         """
+        with self.lock:
+            return load_call_graph(self.db, external, merge_names, implements)
+
+    def export_graph(
+        self,
+        output_format: str = "dot",
+        external: str = ExternalMode.DROP,
+        entry: Optional[str] = None,
+        max_depth: int = 0,
+        export_options: Optional[ExportOptions] = None,
+        by_package: bool = False,
+        self_edges: bool = False,
+        merge_names: bool = False,
+        implements: bool = False,
+        filter_pattern: Optional[str] = None,
+        include_neighbors: bool = False,
+        leaf_prefixes: Optional[List[str]] = None,
+        transparent_funcs: Optional[List[str]] = None,
+    ) -> str:
+        """导出调用图，参数见 export_call_graph()"""
+        return export_call_graph(
+            self,
+            output_format,
+            external,
+            entry,
+            max_depth,
+            export_options,
+            by_package,
+            self_edges,
+            merge_names,
+            implements,
+            filter_pattern,
+            include_neighbors,
+            leaf_prefixes,
+            transparent_funcs,
         )
-        edge_count = 0
-        for row in cursor.fetchall():
-            caller_id = row["caller_id"]
-            callee_id = row["callee_id"]
-            # 只添加两端节点都存在的边
-            if caller_id in valid_node_ids and callee_id in valid_node_ids:
-                lines.append(f'  "{caller_id}" -> "{callee_id}";')
-                edge_count += 1
-
-        lines.append("}")
-
-        # 返回结果，包含统计信息作为注释
-        result = "\n".join(lines)
-        print(f"导出成功: {len(valid_node_ids)} 个节点, {edge_count} 条边")
-
-        return result
 
     def close(self):
         """关闭分析器"""
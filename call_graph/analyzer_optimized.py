@@ -3,58 +3,122 @@
 支持多进程并行处理和批量数据库操作
 """
 
-import os
+import threading
 import time
 from multiprocessing import Pool, cpu_count
+from multiprocessing import TimeoutError as PoolTimeoutError
 from pathlib import Path
-from typing import Any, Dict, List, Optional
+from typing import Any, Dict, List, Optional, Tuple
 
 # 支持相对导入和直接运行
 try:
+    from .analyzer import (
+        DEFAULT_EXCLUDE_DIRS,
+        check_resolution,
+        collect_source_files,
+        export_call_graph,
+        list_package_files,
+        load_call_graph,
+        print_errors,
+        update_files,
+    )
+    from .cancel import CancelToken
     from .database import CallGraphDB
-    from .parsers import LANGUAGE_CONFIG, detect_language, get_parser
+    from .go_build import BuildContext
+    from .graph import CallGraph, ExternalMode
+    from .options import AnalysisOptions, ExportOptions
+    from .parsers import detect_language, get_parser
 except ImportError:
+    from analyzer import (
+        DEFAULT_EXCLUDE_DIRS,
+        check_resolution,
+        collect_source_files,
+        export_call_graph,
+        list_package_files,
+        load_call_graph,
+        print_errors,
+        update_files,
+    )
+    from cancel import CancelToken
     from database import CallGraphDB
-    from parsers import LANGUAGE_CONFIG, detect_language, get_parser
+    from go_build import BuildContext
+    from graph import CallGraph, ExternalMode
+    from options import AnalysisOptions, ExportOptions
+    from parsers import detect_language, get_parser
 
 
-def _process_file_functions(file_path: str) -> List[Dict[str, Any]]:
+# 等待工作进程的结果时检查取消令牌的间隔（秒）
+CANCEL_POLL_INTERVAL = 0.1
+
+# 每个进程池任务处理的文件数，减少进程间通信的次数
+CHUNK_SIZE = 10
+
+# 工作进程的共享状态，由 _init_worker 在每个工作进程启动时设置一次，
+# 避免每个任务都重复 pickle 全部函数定义
+_worker_options: Optional[AnalysisOptions] = None
+_worker_functions: List[Dict[str, Any]] = []
+
+
+def _init_worker(options: AnalysisOptions, all_functions: List[Dict[str, Any]]):
+    """工作进程初始化：保存分析选项和（第二遍扫描用的）全部函数定义"""
+    global _worker_options, _worker_functions
+    _worker_options = options
+    _worker_functions = all_functions
+
+
+def _process_file_functions(
+    file_path: str,
+) -> Tuple[str, List[Dict[str, Any]], Optional[str], Optional[str]]:
     """
     工作进程：从单个文件中提取函数定义
     这个函数必须在模块级别，才能被 multiprocessing pickle
+
+    返回 (file_path, functions, error, syntax_error)，失败时 error 为错误信息；
+    文件有语法错误时 syntax_error 为错误位置，functions 为部分语法树中的结果
     """
     language = detect_language(file_path)
     if not language:
-        return []
+        return file_path, [], None, None
 
     try:
-        parser = get_parser(language)
+        parser = get_parser(language, _worker_options)
         functions = parser.extract_functions(file_path)
-        return functions
+        return file_path, functions, None, parser.syntax_error
     except Exception as e:
         print(f"警告: 提取函数失败 {file_path}: {e}")
-        return []
+        return file_path, [], str(e), None
 
 
-def _process_file_calls(args) -> List[Dict[str, Any]]:
+def _process_file_calls(
+    file_path: str,
+) -> Tuple[str, List[Dict[str, Any]], Optional[str]]:
     """
     工作进程：从单个文件中提取调用关系
-    args: (file_path, all_functions_dict)
+
+    返回 (file_path, calls, error)，失败时 error 为错误信息
     """
-    file_path, all_functions_dict = args
     language = detect_language(file_path)
     if not language:
-        return []
+        return file_path, [], None
 
     try:
-        parser = get_parser(language)
-        # 将 dict 转换回 list
-        all_functions = list(all_functions_dict.values())
-        calls = parser.extract_calls(file_path, all_functions)
-        return calls
+        parser = get_parser(language, _worker_options)
+        calls = parser.extract_calls(file_path, _worker_functions)
+        return file_path, calls, None
     except Exception as e:
         print(f"警告: 提取调用关系失败 {file_path}: {e}")
-        return []
+        return file_path, [], str(e)
+
+
+def _process_chunk(task) -> List[Tuple]:
+    """
+    工作进程：用 worker 依次处理一组文件，task 为 (worker, 文件列表)
+
+    由 _parallel_map 自己分组而不是使用 imap_unordered 的 chunksize，
+    chunksize 大于 1 时返回的迭代器不支持带超时的 next()
+    """
+    worker, files = task
+    return [worker(file_path) for file_path in files]
 
 
 class CallGraphAnalyzerOptimized:
@@ -69,12 +133,20 @@ class CallGraphAnalyzerOptimized:
     """
 
     def __init__(
-        self, db_path: str = "call_graph.db", num_workers: Optional[int] = None
+        self,
+        db_path: str = "call_graph.db",
+        num_workers: Optional[int] = None,
+        options: Optional[AnalysisOptions] = None,
     ):
         self.db = CallGraphDB(db_path)
+        self.options = options or AnalysisOptions()
         self.all_functions: List[Dict[str, Any]] = []
+        # 处理失败的文件，分析结束后汇总到统计结果中
+        self.errors: List[Dict[str, Any]] = []
         # 默认使用 CPU 核心数
         self.num_workers = num_workers or max(1, cpu_count() - 1)
+        # 见 CallGraphAnalyzer.lock
+        self.lock = threading.RLock()
 
     def analyze_project(
         self,
@@ -82,48 +154,101 @@ class CallGraphAnalyzerOptimized:
         exclude_dirs: Optional[List[str]] = None,
         batch_size: int = 100,
         show_progress: bool = True,
+        recursive: bool = True,
+        cancel: Optional[CancelToken] = None,
     ) -> Dict[str, Any]:
         """
         分析整个项目（性能优化版本）
 
+        取消的规则与 CallGraphAnalyzer.analyze_project() 相同；取消时进程池
+        立即终止，不必等待正在处理的文件。
+
         Args:
             project_path: 项目路径
-            exclude_dirs: 排除的目录列表
+            exclude_dirs: 排除的目录列表（默认: DEFAULT_EXCLUDE_DIRS）
             batch_size: 批量插入数据库的大小
             show_progress: 是否显示进度
+            recursive: 是否递归分析子目录
+            cancel: 取消令牌，见 CancelToken
         """
         start_time = time.time()
 
         if exclude_dirs is None:
-            exclude_dirs = [
-                "node_modules",
-                ".git",
-                "__pycache__",
-                "venv",
-                "env",
-                "build",
-                "dist",
-                "target",
-                ".idea",
-                ".vscode",
-                "bin",
-                "obj",
-            ]
+            exclude_dirs = DEFAULT_EXCLUDE_DIRS
 
         project_path = Path(project_path).resolve()
 
         print(f"开始分析项目: {project_path}")
         print(f"使用 {self.num_workers} 个工作进程")
 
+        self.errors = []
+
         # 收集所有源代码文件
-        source_files = self._collect_source_files(project_path, exclude_dirs)
+        source_files = collect_source_files(
+            project_path,
+            exclude_dirs,
+            recursive,
+            self.options.include_tests,
+            BuildContext.from_options(self.options),
+        )
+        return self._analyze_files(
+            source_files, batch_size, show_progress, start_time, cancel
+        )
+
+    def analyze_packages(
+        self,
+        patterns: List[str],
+        directory: str = ".",
+        batch_size: int = 100,
+        show_progress: bool = True,
+        cancel: Optional[CancelToken] = None,
+    ) -> Dict[str, Any]:
+        """
+        按 Go 包模式分析（性能优化版本），见 CallGraphAnalyzer.analyze_packages
+
+        Args:
+            patterns: 包模式，如 ./...、example.com/foo/...
+            directory: 运行 go list 的目录
+            batch_size: 批量插入数据库的大小
+            show_progress: 是否显示进度
+            cancel: 取消令牌，见 CancelToken
+        """
+        start_time = time.time()
+
+        print(f"开始分析 Go 包: {' '.join(patterns)}")
+        print(f"使用 {self.num_workers} 个工作进程")
+
+        self.errors = []
+
+        source_files, options = list_package_files(self, patterns, directory)
+        original = self.options
+        self.options = options
+        try:
+            return self._analyze_files(
+                source_files, batch_size, show_progress, start_time, cancel
+            )
+        finally:
+            self.options = original
+
+    def _analyze_files(
+        self,
+        source_files: List[str],
+        batch_size: int,
+        show_progress: bool,
+        start_time: float,
+        cancel: Optional[CancelToken] = None,
+    ) -> Dict[str, Any]:
+        """并行分析收集到的源文件（两遍扫描）并返回统计结果"""
+        cancel = cancel or CancelToken()
         total_files = len(source_files)
 
         print(f"找到 {total_files} 个源代码文件")
 
         # 第一遍：并行提取所有函数定义
         print(f"\n第一遍扫描：提取函数定义（并行处理）...")
-        functions_list = self._parallel_extract_functions(source_files, show_progress)
+        functions_list = self._parallel_extract_functions(
+            source_files, show_progress, cancel
+        )
 
         # 合并结果
         self.all_functions = []
@@ -132,13 +257,9 @@ class CallGraphAnalyzerOptimized:
 
         print(f"共提取 {len(self.all_functions)} 个函数定义")
 
-        # 批量保存函数定义到数据库
-        print(f"\n保存函数定义到数据库（批量操作，批次大小：{batch_size}）...")
-        self._batch_insert_symbols(self.all_functions, batch_size, show_progress)
-
         # 第二遍：并行提取调用关系
         print(f"\n第二遍扫描：提取调用关系（并行处理）...")
-        calls_list = self._parallel_extract_calls(source_files, show_progress)
+        calls_list = self._parallel_extract_calls(source_files, show_progress, cancel)
 
         # 合并结果
         all_calls = []
@@ -147,9 +268,13 @@ class CallGraphAnalyzerOptimized:
 
         print(f"共提取 {len(all_calls)} 个调用关系")
 
-        # 批量保存调用关系
-        print(f"\n保存调用关系到数据库（批量操作，批次大小：{batch_size}）...")
-        self._batch_insert_calls(all_calls, batch_size, show_progress)
+        # 函数定义和调用关系在一个事务中保存，取消时全部回滚（见 CallGraphDB.transaction）
+        print(f"\n保存到数据库（批量操作，批次大小：{batch_size}）...")
+        with self.lock, self.db.transaction():
+            self._batch_insert_symbols(
+                self.all_functions, batch_size, show_progress, cancel
+            )
+            self._batch_insert_calls(all_calls, batch_size, show_progress, cancel)
 
         # 生成统计报告
         stats = self.db.get_statistics()
@@ -168,109 +293,141 @@ class CallGraphAnalyzerOptimized:
             print(f"  {lang:15s}: {count:6d} 个符号")
         print("=" * 60)
 
+        print_errors(self.errors)
+        check_resolution(self)
+
         stats["elapsed_time"] = elapsed_time
         stats["files_per_second"] = total_files / elapsed_time
+        stats["errors"] = self.errors
 
         return stats
 
-    def _collect_source_files(
-        self, project_path: Path, exclude_dirs: List[str]
-    ) -> List[str]:
-        """收集所有源代码文件"""
-        source_files = []
-
-        # 收集所有支持的扩展名
-        supported_extensions = set()
-        for config in LANGUAGE_CONFIG.values():
-            supported_extensions.update(config["extensions"])
-
-        for root, dirs, files in os.walk(project_path):
-            # 排除指定目录
-            dirs[:] = [
-                d for d in dirs if d not in exclude_dirs and not d.startswith(".")
-            ]
-
-            for file in files:
-                if any(file.endswith(ext) for ext in supported_extensions):
-                    file_path = os.path.join(root, file)
-                    source_files.append(file_path)
-
-        return source_files
+    def _record_error(self, file_path: str, stage: str, error: Optional[str]):
+        """记录工作进程返回的文件错误"""
+        if error is not None:
+            self.errors.append({"file": file_path, "stage": stage, "error": error})
 
     def _parallel_extract_functions(
-        self, source_files: List[str], show_progress: bool = True
+        self,
+        source_files: List[str],
+        show_progress: bool = True,
+        cancel: Optional[CancelToken] = None,
     ) -> List[List[Dict]]:
         """
         并行提取函数定义
         """
-        total = len(source_files)
-        results = []
-
-        with Pool(processes=self.num_workers) as pool:
-            if show_progress:
-                # 使用 imap 可以显示进度
-                processed = 0
-                for result in pool.imap_unordered(
-                    _process_file_functions, source_files, chunksize=10
-                ):
-                    results.append(result)
-                    processed += 1
-                    if processed % 50 == 0 or processed == total:
-                        self._print_progress(processed, total, "提取函数")
-                print()  # 换行
-            else:
-                results = pool.map(_process_file_functions, source_files, chunksize=10)
+        results = self._parallel_map(
+            _process_file_functions,
+            source_files,
+            [],
+            "提取函数",
+            show_progress,
+            cancel,
+        )
+
+        functions_list = []
+        for file_path, functions, error, syntax_error in results:
+            self._record_error(file_path, "functions", error)
+            self._record_error(file_path, "parse", syntax_error)
+            functions_list.append(functions)
 
-        return results
+        return functions_list
 
     def _parallel_extract_calls(
-        self, source_files: List[str], show_progress: bool = True
+        self,
+        source_files: List[str],
+        show_progress: bool = True,
+        cancel: Optional[CancelToken] = None,
     ) -> List[List[Dict]]:
         """
         并行提取调用关系
         """
+        results = self._parallel_map(
+            _process_file_calls,
+            source_files,
+            self.all_functions,
+            "提取调用",
+            show_progress,
+            cancel,
+        )
+
+        calls_list = []
+        for file_path, calls, error in results:
+            self._record_error(file_path, "calls", error)
+            calls_list.append(calls)
+
+        return calls_list
+
+    def _parallel_map(
+        self,
+        worker,
+        source_files: List[str],
+        all_functions: List[Dict[str, Any]],
+        label: str,
+        show_progress: bool,
+        cancel: Optional[CancelToken] = None,
+    ) -> List[Tuple]:
+        """
+        在进程池中处理所有文件
+
+        文件完成的顺序是不确定的，结果会按 source_files 的顺序（已排序）
+        重新排列后返回，保证多次运行合并出的符号和调用关系顺序完全一致。
+        等待结果时每隔 CANCEL_POLL_INTERVAL 秒检查一次 cancel，取消时抛出的
+        AnalysisCancelled 离开 with 块，进程池随之终止（terminate），
+        不会留下仍在运行的工作进程
+        """
+        cancel = cancel or CancelToken()
         total = len(source_files)
-        results = []
-
-        # 创建函数字典（用于传递给工作进程）
-        # 使用 dict 减少数据传输量
-        functions_dict = {
-            f"{func['name']}:{func['file']}": func for func in self.all_functions
-        }
-
-        # 准备参数
-        args_list = [(file_path, functions_dict) for file_path in source_files]
-
-        with Pool(processes=self.num_workers) as pool:
-            if show_progress:
-                processed = 0
-                for result in pool.imap_unordered(
-                    _process_file_calls, args_list, chunksize=10
-                ):
-                    results.append(result)
-                    processed += 1
-                    if processed % 50 == 0 or processed == total:
-                        self._print_progress(processed, total, "提取调用")
+        by_file = {}
+
+        with Pool(
+            processes=self.num_workers,
+            initializer=_init_worker,
+            initargs=(self.options, all_functions),
+        ) as pool:
+            chunks = [
+                (worker, source_files[i : i + CHUNK_SIZE])
+                for i in range(0, total, CHUNK_SIZE)
+            ]
+            # 使用 imap_unordered 可以尽早拿到结果并显示进度
+            results = pool.imap_unordered(_process_chunk, chunks)
+            done = 0
+            for _ in chunks:
+                chunk = None
+                while chunk is None:
+                    cancel.check()
+                    try:
+                        chunk = results.next(timeout=CANCEL_POLL_INTERVAL)
+                    except PoolTimeoutError:
+                        pass
+                for result in chunk:
+                    by_file[result[0]] = result
+                done += len(chunk)
+                if show_progress and (done % 50 == 0 or done == total):
+                    self._print_progress(done, total, label)
+            if show_progress and total:
                 print()  # 换行
-            else:
-                results = pool.map(_process_file_calls, args_list, chunksize=10)
 
-        return results
+        return [by_file[file_path] for file_path in source_files]
 
     def _batch_insert_symbols(
-        self, symbols: List[Dict], batch_size: int, show_progress: bool = True
+        self,
+        symbols: List[Dict],
+        batch_size: int,
+        show_progress: bool = True,
+        cancel: Optional[CancelToken] = None,
     ):
         """
-        批量插入符号到数据库
+        批量插入符号到数据库，每个批次之前检查 cancel
         """
+        cancel = cancel or CancelToken()
         total = len(symbols)
         inserted = 0
 
         # 使用事务批量插入
-        self.db.conn.execute("BEGIN TRANSACTION")
-
-        try:
+        with self.db.transaction():
             for i in range(0, total, batch_size):
+                cancel.check()
                 batch = symbols[i : i + batch_size]
                 for symbol in batch:
                     self.db.insert_symbol(symbol)
@@ -280,28 +437,27 @@ class CallGraphAnalyzerOptimized:
                 if show_progress and (inserted % 500 == 0 or inserted == total):
                     self._print_progress(inserted, total, "保存符号")
 
-            self.db.conn.commit()
-
-            if show_progress:
-                print()  # 换行
-        except Exception as e:
-            self.db.conn.rollback()
-            raise e
+        if show_progress:
+            print()  # 换行
 
     def _batch_insert_calls(
-        self, calls: List[Dict], batch_size: int, show_progress: bool = True
+        self,
+        calls: List[Dict],
+        batch_size: int,
+        show_progress: bool = True,
+        cancel: Optional[CancelToken] = None,
     ):
         """
-        批量插入调用关系到数据库
+        批量插入调用关系到数据库，每个批次之前检查 cancel
         """
+        cancel = cancel or CancelToken()
         total = len(calls)
         inserted = 0
 
         # 使用事务批量插入
-        self.db.conn.execute("BEGIN TRANSACTION")
-
-        try:
+        with self.db.transaction():
             for i in range(0, total, batch_size):
+                cancel.check()
                 batch = calls[i : i + batch_size]
                 for call in batch:
                     self.db.insert_call_relation(call)
@@ -311,13 +467,8 @@ class CallGraphAnalyzerOptimized:
                 if show_progress and (inserted % 1000 == 0 or inserted == total):
                     self._print_progress(inserted, total, "保存调用")
 
-            self.db.conn.commit()
-
-            if show_progress:
-                print()  # 换行
-        except Exception as e:
-            self.db.conn.rollback()
-            raise e
+        if show_progress:
+            print()  # 换行
 
     def _print_progress(self, current: int, total: int, task: str):
         """
@@ -333,6 +484,14 @@ class CallGraphAnalyzerOptimized:
         )
 
     # 保留原有的查询方法
+    def update(self, changed_files: List[str]) -> Dict[str, Any]:
+        """
+        增量更新，见 CallGraphAnalyzer.update()（变化的文件通常很少，
+        在当前进程中解析，不启动进程池）
+        """
+        with self.lock:
+            return update_files(self, changed_files)
+
     def query_callers(self, function_name: str) -> List[Dict[str, Any]]:
         """查询调用指定函数的所有函数"""
         return self.db.get_callers(function_name)
@@ -341,15 +500,23 @@ class CallGraphAnalyzerOptimized:
         """查询指定函数调用的所有函数"""
         return self.db.get_callees(function_name)
 
-    def query_call_chain(self, function_name: str, depth: int = 5) -> List[List[str]]:
-        """查询函数的调用链（向下）"""
-        return self.db.get_call_chain(function_name, depth)
+    def query_call_chain(
+        self, function_name: str, depth: int = 5, merge_names: bool = False
+    ) -> List[List[str]]:
+        """查询函数的调用链（向下），merge_names 见 CallGraphDB.get_call_chain"""
+        return self.db.get_call_chain(function_name, depth, merge_names)
 
     def query_full_call_paths(
-        self, function_name: str, max_depth: int = 10, max_paths: int = 1000
+        self,
+        function_name: str,
+        max_depth: int = 10,
+        max_paths: int = 1000,
+        merge_names: bool = False,
     ) -> Dict[str, Any]:
         """查询函数的完整调用路径（向上+向下）"""
-        return self.db.get_full_call_paths(function_name, max_depth, max_paths)
+        return self.db.get_full_call_paths(
+            function_name, max_depth, max_paths, merge_names
+        )
 
     def search_functions(self, pattern: str) -> List[Dict[str, Any]]:
         """搜索函数"""
@@ -359,59 +526,56 @@ class CallGraphAnalyzerOptimized:
         """获取统计信息"""
         return self.db.get_statistics()
 
-    def export_graph(self, output_format: str = "dot") -> str:
-        """导出调用图"""
-        if output_format == "dot":
-            return self._export_dot()
-        else:
-            raise ValueError(f"不支持的导出格式: {output_format}")
-
-    def _export_dot(self) -> str:
-        """导出为Graphviz DOT格式"""
-        lines = ["digraph CallGraph {"]
-        lines.append("  rankdir=LR;")
-        lines.append("  node [shape=box];")
-        lines.append('  graph [fontname="Arial", fontsize=10];')
-        lines.append('  node [fontname="Arial", fontsize=9];')
-        lines.append('  edge [fontname="Arial", fontsize=8];')
-
-        # 添加所有函数节点
-        symbols = self.db.get_symbols_by_kind("function")
-        valid_node_ids = set()
-        for symbol in symbols:
-            node_id = symbol["id"]
-            valid_node_ids.add(node_id)
-            # 转义特殊字符
-            name = symbol["name"].replace('"', '\\"')
-            file_path = symbol["file"].replace('"', '\\"')
-            label = f"{name}\\n({file_path}:{symbol.get('start_line', '?')})"
-            lines.append(f'  "{node_id}" [label="{label}"];')
-
-        # 添加调用边（只添加有效的边）
-        cursor = self.db.conn.cursor()
-        cursor.execute(
-            """
-            SELECT caller_id, callee_id, caller_name, callee_name 
-            FROM call_relations 
-            WHERE caller_id IS NOT NULL AND callee_id IS NOT NULL
+    def load_graph(
+        self,
+        external: str = ExternalMode.DROP,
+        merge_names: bool = False,
+        implements: bool = False,
+    ) -> CallGraph:
+        """
+        把数据库中的函数和调用关系加载为内存调用图（适合大量重复查询）
+
+        Args:
+            external: 外部调用的处理方式，见 ExternalMode（默认丢弃）
+            merge_names: 把不同包中的同名函数合并为一个节点（默认按限定名区分）
+            implements: 添加 Go 的类型节点和 implements 边，见 load_call_graph()
         """
+        with self.lock:
+            return load_call_graph(self.db, external, merge_names, implements)
+
+    def export_graph(
+        self,
+        output_format: str = "dot",
+        external: str = ExternalMode.DROP,
+        entry: Optional[str] = None,
+        max_depth: int = 0,
+        export_options: Optional[ExportOptions] = None,
+        by_package: bool = False,
+        self_edges: bool = False,
+        merge_names: bool = False,
+        implements: bool = False,
+        filter_pattern: Optional[str] = None,
+        include_neighbors: bool = False,
+        leaf_prefixes: Optional[List[str]] = None,
+        transparent_funcs: Optional[List[str]] = None,
+    ) -> str:
+        """导出调用图，参数见 export_call_graph()"""
+        return export_call_graph(
+            self,
+            output_format,
+            external,
+            entry,
+            max_depth,
+            export_options,
+            by_package,
+            self_edges,
+            merge_names,
+            implements,
+            filter_pattern,
+            include_neighbors,
+            leaf_prefixes,
+            transparent_funcs,
         )
-        edge_count = 0
-        for row in cursor.fetchall():
-            caller_id = row["caller_id"]
-            callee_id = row["callee_id"]
-            # 只添加两端节点都存在的边
-            if caller_id in valid_node_ids and callee_id in valid_node_ids:
-                lines.append(f'  "{caller_id}" -> "{callee_id}";')
-                edge_count += 1
-
-        lines.append("}")
-
-        # 返回结果，包含统计信息作为注释
-        result = "\n".join(lines)
-        print(f"导出成功: {len(valid_node_ids)} 个节点, {edge_count} 条边")
-
-        return result
 
     def close(self):
         """关闭分析器"""
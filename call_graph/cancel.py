@@ -0,0 +1,48 @@
+"""
+取消长时间运行的分析
+嵌入到服务器等程序中时，可以从其他线程取消分析或者设置超时时间
+"""
+
+import threading
+import time
+from typing import Optional
+
+
+class AnalysisCancelled(Exception):
+    """分析被取消或超时，本次分析的结果没有写入数据库"""
+
+
+class CancelToken:
+    """
+    取消令牌：在其他线程中调用 cancel()，或者超过 timeout 秒后，
+    分析会在处理下一个文件之前（以及保存到数据库的过程中）抛出 AnalysisCancelled
+
+    令牌只表示是否应该停止，可以在多次分析之间共享（一次取消，全部停止）；
+    timeout 从创建令牌时开始计算。
+    """
+
+    def __init__(self, timeout: Optional[float] = None):
+        if timeout is not None and timeout <= 0:
+            raise ValueError(f"无效的超时时间: {timeout}")
+        self.timeout = timeout
+        self._deadline = time.monotonic() + timeout if timeout is not None else None
+        self._event = threading.Event()
+
+    def cancel(self):
+        """请求取消（线程安全，可以重复调用）"""
+        self._event.set()
+
+    @property
+    def cancelled(self) -> bool:
+        """是否已经取消或超时"""
+        return self._event.is_set() or self._expired()
+
+    def check(self):
+        """已经取消或超时时抛出 AnalysisCancelled"""
+        if self._event.is_set():
+            raise AnalysisCancelled("分析已取消")
+        if self._expired():
+            raise AnalysisCancelled(f"分析超时（{self.timeout:g} 秒）")
+
+    def _expired(self) -> bool:
+        return self._deadline is not None and time.monotonic() >= self._deadline
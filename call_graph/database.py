@@ -4,8 +4,15 @@
 
 import json
 import sqlite3
+from contextlib import contextmanager
 from pathlib import Path
-from typing import Any, Dict, List, Optional
+from typing import Any, Dict, List, Optional, Tuple
+
+# 旧版本数据库中缺少的列：{表名: {列名: 列定义}}
+SCHEMA_MIGRATIONS = {
+    "symbols": {"package": "TEXT", "receiver": "TEXT", "start_column": "INTEGER"},
+    "call_relations": {"kind": "TEXT DEFAULT 'direct'"},
+}
 
 
 class CallGraphDB:
@@ -14,19 +21,59 @@ class CallGraphDB:
     def __init__(self, db_path: str = "call_graph.db"):
         self.db_path = db_path
         self.conn = None
+        # transaction() 的嵌套层数，大于 0 时写操作不单独提交
+        self._transaction_depth = 0
         self.initialize()
 
     def initialize(self):
         """初始化数据库"""
-        self.conn = sqlite3.connect(self.db_path)
+        # 允许在其他线程中使用（由调用方加锁，见 CallGraphAnalyzer.lock）
+        self.conn = sqlite3.connect(self.db_path, check_same_thread=False)
         self.conn.row_factory = sqlite3.Row
 
         # 读取并执行schema
         schema_path = Path(__file__).parent.parent / "init_db.sql"
         with open(schema_path, "r", encoding="utf-8") as f:
             self.conn.executescript(f.read())
+        self._migrate()
         self.conn.commit()
 
+    def _migrate(self):
+        """为旧版本创建的数据库补充新增的列"""
+        cursor = self.conn.cursor()
+        for table, columns in SCHEMA_MIGRATIONS.items():
+            cursor.execute(f"PRAGMA table_info({table})")
+            existing = {row["name"] for row in cursor.fetchall()}
+            for column, definition in columns.items():
+                if column not in existing:
+                    cursor.execute(
+                        f"ALTER TABLE {table} ADD COLUMN {column} {definition}"
+                    )
+
+    @contextmanager
+    def transaction(self):
+        """
+        把 with 块中的写操作作为一个事务：正常结束时一起提交，发生异常时
+        （包括分析被取消）全部回滚，数据库保持进入之前的状态。可以嵌套，
+        只有最外层的 with 块会提交或回滚
+        """
+        self._transaction_depth += 1
+        try:
+            yield
+        except BaseException:
+            self._transaction_depth -= 1
+            if not self._transaction_depth:
+                self.conn.rollback()
+            raise
+        self._transaction_depth -= 1
+        if not self._transaction_depth:
+            self.conn.commit()
+
+    def _commit(self):
+        """提交单个写操作，在 transaction() 中时由事务统一提交"""
+        if not self._transaction_depth:
+            self.conn.commit()
+
     def insert_symbol(self, symbol: Dict[str, Any]):
         """插入符号信息"""
         cursor = self.conn.cursor()
@@ -34,8 +81,9 @@ class CallGraphDB:
             """
             INSERT OR REPLACE INTO symbols 
             (id, file, name, kind, start_line, end_line, start_byte, end_byte,
-             container, signature, language, extras_json, code_excerpt, is_exported)
-            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+             container, signature, language, extras_json, code_excerpt, is_exported,
+             package, receiver, start_column)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
         """,
             (
                 symbol["id"],
@@ -52,9 +100,12 @@ class CallGraphDB:
                 json.dumps(symbol.get("extras", {})),
                 symbol.get("code_excerpt"),
                 symbol.get("is_exported", 0),
+                symbol.get("package"),
+                symbol.get("receiver"),
+                symbol.get("start_column"),
             ),
         )
-        self.conn.commit()
+        self._commit()
 
     def insert_call_relation(self, relation: Dict[str, Any]):
         """插入调用关系"""
@@ -63,8 +114,8 @@ class CallGraphDB:
             """
             INSERT INTO call_relations 
             (caller_id, callee_id, caller_name, callee_name, caller_file, 
-             callee_file, call_site_line, call_site_column, language)
-            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+             callee_file, call_site_line, call_site_column, language, kind)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
         """,
             (
                 relation["caller_id"],
@@ -76,9 +127,10 @@ class CallGraphDB:
                 relation.get("call_site_line"),
                 relation.get("call_site_column"),
                 relation["language"],
+                relation.get("kind", "direct"),
             ),
         )
-        self.conn.commit()
+        self._commit()
 
     def get_callers(self, function_name: str) -> List[Dict[str, Any]]:
         """查询调用指定函数的所有函数"""
@@ -106,40 +158,148 @@ class CallGraphDB:
         )
         return [dict(row) for row in cursor.fetchall()]
 
-    def get_call_chain(self, function_name: str, depth: int = 5) -> List[List[str]]:
-        """查询函数的调用链（从该函数向下递归查询）"""
+    def get_callers_by_id(self, symbol_id: str) -> List[Dict[str, Any]]:
+        """按符号 ID 查询调用指定函数的所有调用关系"""
+        cursor = self.conn.cursor()
+        cursor.execute(
+            """
+            SELECT * FROM call_relations
+            WHERE callee_id = ?
+            ORDER BY caller_file, call_site_line
+        """,
+            (symbol_id,),
+        )
+        return [dict(row) for row in cursor.fetchall()]
+
+    def get_callees_by_id(self, symbol_id: str) -> List[Dict[str, Any]]:
+        """按符号 ID 查询指定函数发出的所有调用关系"""
+        cursor = self.conn.cursor()
+        cursor.execute(
+            """
+            SELECT * FROM call_relations
+            WHERE caller_id = ?
+            ORDER BY callee_file, call_site_line
+        """,
+            (symbol_id,),
+        )
+        return [dict(row) for row in cursor.fetchall()]
+
+    def get_function_ids(self, function_name: str) -> List[Dict[str, Any]]:
+        """
+        名称（如 User.Greet）或限定名（如 example.com/app/model.User.Greet）
+        匹配的所有函数 [{id, name}]，按文件和行号排序
+        """
+        cursor = self.conn.cursor()
+        cursor.execute(
+            """
+            SELECT id, name FROM symbols
+            WHERE kind = 'function' AND (name = ? OR package || '.' || name = ?)
+            ORDER BY file, start_line
+        """,
+            (function_name, function_name),
+        )
+        return [dict(row) for row in cursor.fetchall()]
+
+    def _ambiguous_function_names(self) -> Dict[str, str]:
+        """在多个包中都有定义的函数: 符号 ID -> 限定名（包路径.函数名）"""
+        cursor = self.conn.cursor()
+        cursor.execute(
+            """
+            SELECT id, package || '.' || name AS qualified FROM symbols
+            WHERE kind = 'function' AND package IS NOT NULL AND name IN (
+                SELECT name FROM symbols
+                WHERE kind = 'function' AND package IS NOT NULL
+                GROUP BY name HAVING COUNT(DISTINCT package) > 1
+            )
+        """
+        )
+        return {row["id"]: row["qualified"] for row in cursor.fetchall()}
+
+    def _traversal(self, function_name: str, merge_names: bool):
+        """
+        调用链遍历使用的 (起点列表, 调用者函数, 被调用者函数)
+
+        节点用 (键, 函数名) 表示。默认按符号 ID 遍历，不同包中的同名函数
+        （如两个包各自的 New）是不同的节点，函数名使用限定名以便区分；
+        merge_names 为 True 时按函数名遍历，所有同名函数被当作同一个节点
+        （旧版本的行为）。
+        """
+        if merge_names:
+            starts = [(function_name, function_name)]
+
+            def callers(key: str):
+                return [(r["caller_name"],) * 2 for r in self.get_callers(key)]
+
+            def callees(key: str):
+                return [(r["callee_name"],) * 2 for r in self.get_callees(key)]
+
+        else:
+            qualified = self._ambiguous_function_names()
+            symbols = self.get_function_ids(function_name)
+            starts = [
+                (symbol["id"], qualified.get(symbol["id"], symbol["name"]))
+                for symbol in symbols
+            ]
+
+            def callers(key: str):
+                return [
+                    (r["caller_id"], qualified.get(r["caller_id"], r["caller_name"]))
+                    for r in self.get_callers_by_id(key)
+                ]
+
+            def callees(key: str):
+                return [
+                    (r["callee_id"], qualified.get(r["callee_id"], r["callee_name"]))
+                    for r in self.get_callees_by_id(key)
+                ]
+
+        return starts, callers, callees
+
+    def get_call_chain(
+        self, function_name: str, depth: int = 5, merge_names: bool = False
+    ) -> List[List[str]]:
+        """
+        查询函数的调用链（从该函数向下递归查询），链中的元素是函数名
+
+        function_name 可以是函数名或限定名，匹配到多个函数时分别作为起点。
+        merge_names 见 _traversal()。
+        """
         chains = []
         visited = set()
+        starts, _, callees_of = self._traversal(function_name, merge_names)
 
-        def dfs(func_name: str, current_chain: List[str], current_depth: int):
-            if current_depth > depth or func_name in visited:
+        def dfs(key: str, func_name: str, current_chain: List[str], current_depth):
+            if current_depth > depth or key in visited:
                 return
 
-            visited.add(func_name)
+            visited.add(key)
             current_chain.append(func_name)
 
-            callees = self.get_callees(func_name)
+            callees = callees_of(key)
             if not callees:
                 chains.append(current_chain.copy())
             else:
-                for callee in callees:
-                    dfs(callee["callee_name"], current_chain.copy(), current_depth + 1)
+                for callee_key, callee_name in callees:
+                    next_depth = current_depth + 1
+                    dfs(callee_key, callee_name, current_chain.copy(), next_depth)
 
-            visited.remove(func_name)
+            visited.remove(key)
 
-        dfs(function_name, [], 0)
+        for key, name in starts:
+            dfs(key, name, [], 0)
         return chains
 
     def get_function_info(self, func_name: str) -> Optional[Dict[str, Any]]:
-        """获取函数的详细信息（文件和行号）"""
+        """获取函数的详细信息（文件和行号），func_name 可以是函数名或限定名"""
         cursor = self.conn.cursor()
         cursor.execute(
             """
             SELECT file, start_line FROM symbols 
-            WHERE name = ? AND kind = 'function'
+            WHERE (name = ? OR package || '.' || name = ?) AND kind = 'function'
+            ORDER BY file, start_line
             LIMIT 1
         """,
-            (func_name,),
+            (func_name, func_name),
         )
         row = cursor.fetchone()
         if row:
@@ -147,16 +307,21 @@ class CallGraphDB:
         return None
 
     def get_full_call_paths(
-        self, function_name: str, max_depth: int = 10, max_paths: int = 1000
+        self,
+        function_name: str,
+        max_depth: int = 10,
+        max_paths: int = 1000,
+        merge_names: bool = False,
     ) -> Dict[str, Any]:
         """
         查询函数的完整调用路径（优化版本，支持去重和路径限制）
         返回包含该函数的所有调用链：从入口函数到叶子函数
 
         Args:
-            function_name: 目标函数名称
+            function_name: 目标函数名称（函数名或限定名）
             max_depth: 最大搜索深度（默认10）
             max_paths: 最大路径数量限制，防止超大项目产生过多路径（默认1000）
+            merge_names: 按函数名而不是符号 ID 遍历，见 _traversal()
 
         Returns:
             Dict包含：
@@ -170,14 +335,16 @@ class CallGraphDB:
         import time
 
         start_time = time.time()
+        starts, callers_of, callees_of = self._traversal(function_name, merge_names)
 
+        # 路径中的节点为 (键, 函数名)，见 _traversal()
         # 1. 向上追溯：找到所有到达目标函数的路径（从根到目标）
         paths_from_root = []
         visited_up = set()
         seen_paths_up = set()  # 用于去重
         truncated_up = False
 
-        def trace_up(func_name: str, current_path: List[str], depth: int):
+        def trace_up(node: Tuple[str, str], current_path: List, depth: int):
             """向上追溯到根节点（优化版）"""
             nonlocal truncated_up
 
@@ -186,13 +353,13 @@ class CallGraphDB:
                 truncated_up = True
                 return
 
-            if depth > max_depth or func_name in visited_up:
+            if depth > max_depth or node[0] in visited_up:
                 return
 
-            visited_up.add(func_name)
-            current_path.insert(0, func_name)  # 在路径开头插入
+            visited_up.add(node[0])
+            current_path.insert(0, node)  # 在路径开头插入
 
-            callers = self.get_callers(func_name)
+            callers = callers_of(node[0])
             if not callers:
                 # 到达根节点（没有调用者）
                 # 使用 tuple 进行去重检查（tuple 可哈希，可以放入 set）
@@ -205,9 +372,9 @@ class CallGraphDB:
                     if len(paths_from_root) >= max_paths:
                         truncated_up = True
                         break
-                    trace_up(caller["caller_name"], current_path.copy(), depth + 1)
+                    trace_up(caller, current_path.copy(), depth + 1)
 
-            visited_up.remove(func_name)
+            visited_up.remove(node[0])
 
         # 2. 向下追溯：找到所有从目标函数出发的路径（目标到叶子）
         paths_to_leaf = []
@@ -215,7 +382,7 @@ class CallGraphDB:
         seen_paths_down = set()  # 用于去重
         truncated_down = False
 
-        def trace_down(func_name: str, current_path: List[str], depth: int):
+        def trace_down(node: Tuple[str, str], current_path: List, depth: int):
             """向下追溯到叶子节点（优化版）"""
             nonlocal truncated_down
 
@@ -224,13 +391,13 @@ class CallGraphDB:
                 truncated_down = True
                 return
 
-            if depth > max_depth or func_name in visited_down:
+            if depth > max_depth or node[0] in visited_down:
                 return
 
-            visited_down.add(func_name)
-            current_path.append(func_name)
+            visited_down.add(node[0])
+            current_path.append(node)
 
-            callees = self.get_callees(func_name)
+            callees = callees_of(node[0])
             if not callees:
                 # 到达叶子节点（不调用其他函数）
                 # 去重检查
@@ -243,13 +410,14 @@ class CallGraphDB:
                     if len(paths_to_leaf) >= max_paths:
                         truncated_down = True
                         break
-                    trace_down(callee["callee_name"], current_path.copy(), depth + 1)
+                    trace_down(callee, current_path.copy(), depth + 1)
 
-            visited_down.remove(func_name)
+            visited_down.remove(node[0])
 
-        # 执行追溯
-        trace_up(function_name, [], 0)
-        trace_down(function_name, [], 0)
+        # 执行追溯（匹配到多个同名函数时分别作为目标）
+        for start in starts:
+            trace_up(start, [], 0)
+            trace_down(start, [], 0)
 
         # 3. 组合完整路径（使用 set 去重，限制数量）
         full_paths = []
@@ -265,6 +433,9 @@ class CallGraphDB:
                 if len(full_paths) >= max_paths:
                     truncated_full = True
                     break
+                # 只组合经过同一个目标函数的路径
+                if root_path[-1] != leaf_path[0]:
+                    continue
 
                 # root_path 已经包含目标函数，leaf_path 也包含目标函数
                 # 需要去除重复的目标函数
@@ -282,18 +453,19 @@ class CallGraphDB:
                     full_paths.append(combined)
 
         # 如果没有找到根路径（可能目标函数就是根），使用目标函数作为起点
+        target = (function_name, function_name)
         if not paths_from_root:
-            paths_from_root = [[function_name]]
+            paths_from_root = [[target]]
             if paths_to_leaf:
                 for leaf_path in paths_to_leaf:
                     if len(leaf_path) > 1:
                         full_paths.append(leaf_path)
                     else:
-                        full_paths.append([function_name])
+                        full_paths.append([target])
 
         # 如果没有找到叶子路径（可能目标函数就是叶子），使用目标函数作为终点
         if not paths_to_leaf:
-            paths_to_leaf = [[function_name]]
+            paths_to_leaf = [[target]]
             if not full_paths and paths_from_root:
                 full_paths = paths_from_root
 
@@ -301,43 +473,47 @@ class CallGraphDB:
         # 性能优化：批量获取函数信息，减少数据库查询次数
         time_before_detail = time.time()
 
-        # 收集所有需要查询的函数名
-        all_func_names = set()
+        # 收集所有需要查询的函数（按函数名遍历时键就是函数名）
+        all_func_keys = set()
         for path in full_paths:
-            all_func_names.update(path)
+            all_func_keys.update(key for key, _ in path)
 
         # 批量查询函数信息（一次查询）
         func_info_cache = {}
-        if all_func_names:
-            placeholders = ",".join("?" * len(all_func_names))
+        if all_func_keys:
+            key_column = "name" if merge_names else "id"
+            placeholders = ",".join("?" * len(all_func_keys))
             cursor = self.conn.cursor()
             cursor.execute(
                 f"""
-                SELECT name, file, start_line FROM symbols 
-                WHERE name IN ({placeholders}) AND kind = 'function'
+                SELECT {key_column} AS key, file, start_line FROM symbols 
+                WHERE {key_column} IN ({placeholders}) AND kind = 'function'
+                ORDER BY file, start_line
             """,
-                tuple(all_func_names),
+                tuple(all_func_keys),
             )
 
             for row in cursor.fetchall():
-                if row["name"] not in func_info_cache:  # 如果有重名，取第一个
-                    func_info_cache[row["name"]] = {
+                if row["key"] not in func_info_cache:  # 如果有重名，取第一个
+                    func_info_cache[row["key"]] = {
                         "file": row["file"],
                         "line": row["start_line"],
                     }
 
         # 使用缓存构建详细路径
+        target_keys = {key for key, _ in starts} or {function_name}
         full_paths_detailed = []
         for path in full_paths:
             detailed_path = []
-            for func_name in path:
-                info = func_info_cache.get(func_name)
+            for func_key, func_name in path:
+                info = func_info_cache.get(func_key)
                 if info:
                     # 格式：函数名(文件:行号)
                     file_short = info["file"].split("/")[-1]  # 只取文件名
                     detailed_path.append(
                         {
                             "name": func_name,
+                            "target": func_key in target_keys,
                             "file": info["file"],
                             "file_short": file_short,
                             "line": info["line"],
@@ -349,6 +525,7 @@ class CallGraphDB:
                     detailed_path.append(
                         {
                             "name": func_name,
+                            "target": func_key in target_keys,
                             "file": None,
                             "file_short": None,
                             "line": None,
@@ -365,11 +542,14 @@ class CallGraphDB:
         # 检查是否被截断
         is_truncated = truncated_up or truncated_down or truncated_full
 
+        def names(paths: List[List[Tuple[str, str]]]) -> List[List[str]]:
+            return [[name for _, name in path] for path in paths]
+
         return {
             "target_function": function_name,
-            "paths_from_root": paths_from_root,
-            "paths_to_leaf": paths_to_leaf,
-            "full_paths": full_paths,
+            "paths_from_root": names(paths_from_root),
+            "paths_to_leaf": names(paths_to_leaf),
+            "full_paths": names(full_paths),
             "full_paths_detailed": full_paths_detailed,
             "root_count": len(paths_from_root),
             "leaf_count": len(paths_to_leaf),
@@ -382,7 +562,7 @@ class CallGraphDB:
                 "duplicates_removed": len(seen_full_paths) - len(full_paths)
                 if is_truncated
                 else 0,
-                "unique_functions": len(all_func_names),
+                "unique_functions": len(all_func_keys),
             },
         }
 
@@ -410,6 +590,60 @@ class CallGraphDB:
         )
         return [dict(row) for row in cursor.fetchall()]
 
+    def get_symbols_by_language(self, language: str) -> List[Dict[str, Any]]:
+        """查询指定语言的所有符号，extras_json 解析为 extras 字典"""
+        cursor = self.conn.cursor()
+        cursor.execute(
+            """
+            SELECT * FROM symbols WHERE language = ?
+            ORDER BY file, start_line
+        """,
+            (language,),
+        )
+        symbols = []
+        for row in cursor.fetchall():
+            symbol = dict(row)
+            symbol["extras"] = json.loads(symbol.pop("extras_json") or "{}")
+            symbols.append(symbol)
+        return symbols
+
+    def get_all_symbols(self) -> List[Dict[str, Any]]:
+        """查询所有符号（第二遍扫描的输入），extras_json 解析为 extras 字典"""
+        cursor = self.conn.cursor()
+        cursor.execute("SELECT * FROM symbols ORDER BY file, start_line")
+        symbols = []
+        for row in cursor.fetchall():
+            symbol = dict(row)
+            symbol["extras"] = json.loads(symbol.pop("extras_json") or "{}")
+            symbols.append(symbol)
+        return symbols
+
+    def delete_symbols_in_files(self, files: List[str]):
+        """删除在这些文件中声明的符号"""
+        self.conn.executemany(
+            "DELETE FROM symbols WHERE file = ?", [(f,) for f in files]
+        )
+        self._commit()
+
+    def delete_calls_in_files(self, files: List[str]):
+        """删除调用位置在这些文件中的调用关系"""
+        self.conn.executemany(
+            "DELETE FROM call_relations WHERE caller_file = ?", [(f,) for f in files]
+        )
+        self._commit()
+
+    def get_call_relations(self) -> List[Dict[str, Any]]:
+        """查询所有调用关系"""
+        cursor = self.conn.cursor()
+        cursor.execute(
+            """
+            SELECT * FROM call_relations
+            WHERE caller_id IS NOT NULL AND callee_id IS NOT NULL
+            ORDER BY caller_file, call_site_line, call_site_column
+        """
+        )
+        return [dict(row) for row in cursor.fetchall()]
+
     def search_symbols(self, pattern: str) -> List[Dict[str, Any]]:
         """模糊搜索符号名称"""
         cursor = self.conn.cursor()
@@ -463,7 +697,7 @@ class CallGraphDB:
         cursor = self.conn.cursor()
         cursor.execute("DELETE FROM call_relations")
         cursor.execute("DELETE FROM symbols")
-        self.conn.commit()
+        self._commit()
 
     def close(self):
         """关闭数据库连接"""
@@ -0,0 +1,168 @@
+"""
+调用图对比
+比较两个版本的调用图（例如 PR 的基准提交和最新提交），找出新增和删除的函数与调用
+"""
+
+from dataclasses import dataclass, field
+from typing import Dict, List, Tuple
+
+# 支持相对导入和直接运行
+try:
+    from .exporters import _dot_escape
+    from .graph import CallGraph, Edge, EdgeKind, Node
+except ImportError:
+    from exporters import _dot_escape
+    from graph import CallGraph, Edge, EdgeKind, Node
+
+# 对比中调用边的标识：(调用者限定名, 被调用者限定名, 边类型)
+EdgeKey = Tuple[str, str, str]
+
+# DOT 导出时新增/删除的节点和边的颜色
+DOT_ADDED_COLOR = "green3"
+DOT_REMOVED_COLOR = "red"
+
+
+@dataclass
+class GraphDiff:
+    """
+    两个调用图之间的差异
+
+    函数按限定名（包名.函数名）对应，不比较文件和行号：函数移动位置不算变化，
+    重命名表现为删除旧函数并新增新函数。新增的节点和边来自新调用图，
+    删除的节点和边来自旧调用图，都按各自调用图中的顺序排序。
+    """
+
+    old: CallGraph
+    new: CallGraph
+    added_nodes: List[Node] = field(default_factory=list)
+    removed_nodes: List[Node] = field(default_factory=list)
+    added_edges: List[Edge] = field(default_factory=list)
+    removed_edges: List[Edge] = field(default_factory=list)
+
+    def is_empty(self) -> bool:
+        """两个调用图是否没有差异"""
+        return not (
+            self.added_nodes
+            or self.removed_nodes
+            or self.added_edges
+            or self.removed_edges
+        )
+
+    def to_text(self) -> str:
+        """类似 diff 的文本格式，+ 为新增，- 为删除"""
+        lines = []
+        for node in self.removed_nodes:
+            lines.append(f"- 函数 {node.qualified_name}")
+        for node in self.added_nodes:
+            lines.append(f"+ 函数 {node.qualified_name}")
+        for edge in self.removed_edges:
+            lines.append(f"- 调用 {_edge_text(self.old, edge)}")
+        for edge in self.added_edges:
+            lines.append(f"+ 调用 {_edge_text(self.new, edge)}")
+        return "\n".join(lines)
+
+    def to_dot(self) -> str:
+        """
+        导出为 Graphviz DOT 格式
+
+        只包含发生变化的函数和调用，以及变化的调用两端的函数：
+        新增的显示为绿色，删除的显示为红色（虚线），其他节点使用默认样式。
+        节点 ID 使用限定名，新旧调用图中的同一个函数对应同一个节点。
+        """
+        # 限定名 -> 颜色（空字符串表示没有变化的节点）
+        nodes: Dict[str, str] = {}
+        for node in self.removed_nodes:
+            nodes[node.qualified_name] = DOT_REMOVED_COLOR
+        for node in self.added_nodes:
+            nodes[node.qualified_name] = DOT_ADDED_COLOR
+        changed_edges = ((self.old, self.removed_edges), (self.new, self.added_edges))
+        for graph, edges in changed_edges:
+            for edge in edges:
+                for node_id in (edge.caller, edge.callee):
+                    nodes.setdefault(graph.nodes[node_id].qualified_name, "")
+
+        lines = ["digraph CallGraphDiff {"]
+        lines.append("  rankdir=LR;")
+        lines.append("  node [shape=box];")
+        lines.append('  graph [fontname="Arial", fontsize=10];')
+        lines.append('  node [fontname="Arial", fontsize=9];')
+        lines.append('  edge [fontname="Arial", fontsize=8];')
+
+        for name in sorted(nodes):
+            color = nodes[name]
+            attributes = [f'label="{_dot_escape(name)}"']
+            if color:
+                attributes.append(f'color="{color}", fontcolor="{color}"')
+            if color == DOT_REMOVED_COLOR:
+                attributes.append("style=dashed")
+            lines.append(f'  "{_dot_escape(name)}" [{", ".join(attributes)}];')
+
+        for graph, edges, color in (
+            (self.old, self.removed_edges, DOT_REMOVED_COLOR),
+            (self.new, self.added_edges, DOT_ADDED_COLOR),
+        ):
+            for edge in edges:
+                caller, callee, kind = _edge_key(graph, edge)
+                attributes = [f'color="{color}"']
+                if color == DOT_REMOVED_COLOR:
+                    attributes.append("style=dashed")
+                if kind != EdgeKind.DIRECT:
+                    attributes.append(f'label="{kind}", fontcolor="{color}"')
+                lines.append(
+                    f'  "{_dot_escape(caller)}" -> "{_dot_escape(callee)}" '
+                    f'[{", ".join(attributes)}];'
+                )
+
+        lines.append("}")
+        return "\n".join(lines)
+
+
+def diff(old: CallGraph, new: CallGraph) -> GraphDiff:
+    """
+    对比两个调用图
+
+    没有包信息的函数（Go 以外的语言）的限定名就是函数名，
+    多个同名函数在对比中被视为同一个函数。
+    """
+    old_nodes = _nodes_by_name(old)
+    new_nodes = _nodes_by_name(new)
+    old_edges = _edges_by_key(old)
+    new_edges = _edges_by_key(new)
+
+    return GraphDiff(
+        old=old,
+        new=new,
+        added_nodes=[n for name, n in new_nodes.items() if name not in old_nodes],
+        removed_nodes=[n for name, n in old_nodes.items() if name not in new_nodes],
+        added_edges=[e for key, e in new_edges.items() if key not in old_edges],
+        removed_edges=[e for key, e in old_edges.items() if key not in new_edges],
+    )
+
+
+def _nodes_by_name(graph: CallGraph) -> Dict[str, Node]:
+    nodes: Dict[str, Node] = {}
+    for node in graph.sorted_nodes():
+        nodes.setdefault(node.qualified_name, node)
+    return nodes
+
+
+def _edges_by_key(graph: CallGraph) -> Dict[EdgeKey, Edge]:
+    edges: Dict[EdgeKey, Edge] = {}
+    for edge in graph.sorted_edges():
+        edges.setdefault(_edge_key(graph, edge), edge)
+    return edges
+
+
+def _edge_key(graph: CallGraph, edge: Edge) -> EdgeKey:
+    return (
+        graph.nodes[edge.caller].qualified_name,
+        graph.nodes[edge.callee].qualified_name,
+        edge.kind,
+    )
+
+
+def _edge_text(graph: CallGraph, edge: Edge) -> str:
+    caller, callee, kind = _edge_key(graph, edge)
+    if kind == EdgeKind.DIRECT:
+        return f"{caller} -> {callee}"
+    return f"{caller} -> {callee} ({kind})"
@@ -0,0 +1,651 @@
+"""
+调用图导出
+把 CallGraph 转换为 Graphviz DOT、Mermaid、GraphML、CSV、SVG 等文本格式
+"""
+
+import csv
+import io
+import math
+import re
+import shutil
+import subprocess
+from collections import defaultdict, deque
+from dataclasses import dataclass
+from typing import Dict, List, Optional, Tuple
+from xml.sax.saxutils import escape
+
+# 支持相对导入和直接运行
+try:
+    from .graph import CallGraph, EdgeKind, Node
+    from .options import ExportOptions
+except ImportError:
+    from graph import CallGraph, EdgeKind, Node
+    from options import ExportOptions
+
+# DOT 导出时各类调用边的样式，未列出的类型使用默认样式
+DOT_EDGE_STYLES = {
+    EdgeKind.INTERFACE: 'style=dashed, color="blue"',
+    EdgeKind.GO: "style=bold",
+    EdgeKind.DEFER: "style=dashed",
+    EdgeKind.UNRESOLVED: 'style=dotted, color="orange"',
+    EdgeKind.AMBIGUOUS: 'style=dashed, color="purple"',
+    EdgeKind.IMPLEMENTS: 'style=dotted, arrowhead=empty, color="gray40"',
+}
+
+# DOT 导出时外部函数节点的样式
+DOT_EXTERNAL_STYLE = 'shape=ellipse, style=dashed, color="gray50"'
+
+# DOT 导出时类型节点（implements 边的两端）的样式
+DOT_TYPE_STYLE = "shape=component"
+
+# DOT 导出时高亮路径上的节点和边、以及其他被淡化的节点和边的样式
+DOT_HIGHLIGHT_STYLE = 'color="red", fontcolor="red", penwidth=2'
+DOT_DIMMED_STYLE = 'color="gray80", fontcolor="gray60"'
+
+# DOT 按扇入着色时的默认调色板（ColorBrewer YlOrRd，从扇入最小到最大）
+# 和没有调用者的函数的颜色
+DOT_FAN_IN_PALETTE = ["#ffffb2", "#fecc5c", "#fd8d3c", "#f03b20", "#bd0026"]
+DOT_NO_CALLERS_COLOR = "#9ecae1"
+
+# Mermaid 导出时各类调用边的连线，未列出的类型使用实线箭头
+MERMAID_EDGE_ARROWS = {
+    EdgeKind.INTERFACE: "-.->",
+    EdgeKind.GO: "== go ==>",
+    EdgeKind.DEFER: "-. defer .->",
+    EdgeKind.UNRESOLVED: "-. unresolved .->",
+    EdgeKind.AMBIGUOUS: "-. ambiguous .->",
+    EdgeKind.IMPLEMENTS: "-. implements .->",
+}
+
+
+def short_name(node: Node) -> str:
+    """只显示函数名或方法名（User.Greet 显示为 Greet）"""
+    if node.receiver and node.name.startswith(node.receiver + "."):
+        return node.name[len(node.receiver) + 1 :]
+    return node.name
+
+
+def qualified_name(node: Node) -> str:
+    """显示限定名（example.com/app/model.User.Greet）"""
+    return node.qualified_name
+
+
+def with_signature(node: Node) -> str:
+    """
+    限定名加上参数和返回值（example.com/app/model.User.Greet() string），
+    取自声明的第一行；没有声明的节点（外部函数、包）只显示限定名
+    """
+    if not node.signature:
+        return node.qualified_name
+    name = short_name(node)
+    # 去掉 func、接收者和函数名，泛型函数的类型参数保留
+    match = re.search(rf"\b{re.escape(name)}\s*(?=[(\[])", node.signature)
+    if not match:
+        return node.qualified_name
+    rest = node.signature[match.end() :].rstrip().rstrip("{:").rstrip()
+    return node.qualified_name + rest
+
+
+# 节点标签的内置格式，见 ExportOptions.label
+LABEL_FORMATTERS = {
+    "short": short_name,
+    "qualified": qualified_name,
+    "signature": with_signature,
+}
+
+
+def _label(node: Node, options: ExportOptions) -> str:
+    return options.label(node) if options.label else node.name
+
+
+def _dot_escape(text: str) -> str:
+    return text.replace("\\", "\\\\").replace('"', '\\"')
+
+
+def to_dot(graph: CallGraph, options: Optional[ExportOptions] = None) -> str:
+    """导出为Graphviz DOT格式"""
+    options = options or ExportOptions()
+    path = options.highlight_path
+    path_nodes = set(path)
+    path_edges = set(zip(path, path[1:]))
+    fill = fan_in_colors(graph, options) if options.color_by_fan_in else {}
+
+    def node_style(node: Node) -> str:
+        styles = []
+        if node.id in fill:
+            styles.append(f'style=filled, fillcolor="{fill[node.id]}"')
+        if path:
+            in_path = node.id in path_nodes
+            styles.append(DOT_HIGHLIGHT_STYLE if in_path else DOT_DIMMED_STYLE)
+        return ", ".join(styles)
+
+    lines = ["digraph CallGraph {"]
+    lines.append("  rankdir=LR;")
+    lines.append("  node [shape=box];")
+    lines.append('  graph [fontname="Arial", fontsize=10];')
+    lines.append('  node [fontname="Arial", fontsize=9];')
+    lines.append('  edge [fontname="Arial", fontsize=8];')
+
+    # group_methods: 方法（和合并进记录的类型节点）ID -> 边连接的 "记录节点":端口
+    records = _method_records(graph) if options.group_methods else []
+    ports: Dict[str, str] = {}
+    for record in records:
+        record_id = _dot_escape(record.id)
+        if record.type_node:
+            ports[record.type_node.id] = f'"{record_id}":type'
+        for i, method in enumerate(record.methods):
+            ports[method.id] = f'"{record_id}":m{i}'
+
+    def endpoint(node_id: str) -> str:
+        return ports.get(node_id) or f'"{_dot_escape(node_id)}"'
+
+    # (所在的包, 节点语句)，没有包信息的节点（其他语言、外部函数）包为 None
+    statements: List[Tuple[Optional[str], str]] = []
+    for node in graph.sorted_nodes():
+        if node.id not in ports:
+            package = None if node.external else node.package
+            statements.append((package, _dot_node(node, options, node_style(node))))
+    for record in records:
+        style = ""
+        if path:
+            in_path = any(m.id in path_nodes for m in record.members())
+            style = DOT_HIGHLIGHT_STYLE if in_path else DOT_DIMMED_STYLE
+        statements.append((record.package, _dot_record(record, options, style)))
+
+    if options.cluster:
+        # 每个包一个 cluster（方法和其接收者类型在同一个包中），
+        # 没有包信息的节点放在 cluster 之外
+        packages: Dict[str, List[str]] = defaultdict(list)
+        loose = []
+        for package, statement in statements:
+            if package:
+                packages[package].append(statement)
+            else:
+                loose.append(statement)
+
+        for package in sorted(packages):
+            name = _dot_escape(package)
+            lines.append(f'  subgraph "cluster_{name}" {{')
+            lines.append(f'    label="{name}";')
+            for statement in packages[package]:
+                lines.append("  " + statement)
+            lines.append("  }")
+        lines.extend(loose)
+    else:
+        lines.extend(statement for _, statement in statements)
+
+    for edge in graph.sorted_edges():
+        attributes = []
+        style = DOT_EDGE_STYLES.get(edge.kind)
+        if style:
+            attributes.append(style)
+        if edge.weight > 1:
+            # 包级别调用图：权重越大线条越粗（按对数增长，避免过粗）
+            penwidth = 1 + math.log2(edge.weight)
+            attributes.append(f'label="{edge.weight}", penwidth={penwidth:.1f}')
+        elif options.call_counts and edge.count > 1:
+            attributes.append(f'label="{edge.count}"')
+        if path:
+            # 放在最后，覆盖边类型的颜色
+            if (edge.caller, edge.callee) in path_edges:
+                attributes.append(DOT_HIGHLIGHT_STYLE)
+            else:
+                attributes.append(DOT_DIMMED_STYLE)
+        caller = endpoint(edge.caller)
+        callee = endpoint(edge.callee)
+        if attributes:
+            lines.append(f"  {caller} -> {callee} [{', '.join(attributes)}];")
+        else:
+            lines.append(f"  {caller} -> {callee};")
+
+    lines.append("}")
+    return "\n".join(lines)
+
+
+def fan_in_colors(
+    graph: CallGraph, options: Optional[ExportOptions] = None
+) -> Dict[str, str]:
+    """
+    按扇入给函数节点分配颜色，{节点 ID: 颜色}
+
+    有调用者的函数按扇入在图中观察到的最小值和最大值之间线性映射到调色板
+    （所有函数的扇入相同时都使用第一个颜色），没有调用者的函数使用单独的颜色；
+    外部函数和类型节点不着色。
+    """
+    options = options or ExportOptions()
+    palette = options.fan_in_palette or DOT_FAN_IN_PALETTE
+    no_callers = options.no_callers_color or DOT_NO_CALLERS_COLOR
+    metrics = [
+        m
+        for m in graph.metrics().values()
+        if not m.node.external and m.node.kind == "function"
+    ]
+    called = [m.fan_in for m in metrics if m.fan_in]
+    low, high = (min(called), max(called)) if called else (0, 0)
+
+    colors = {}
+    for m in metrics:
+        if not m.fan_in:
+            colors[m.node.id] = no_callers
+        elif high == low:
+            colors[m.node.id] = palette[0]
+        else:
+            scale = (m.fan_in - low) / (high - low)
+            colors[m.node.id] = palette[round(scale * (len(palette) - 1))]
+    return colors
+
+
+def _dot_node(node: Node, options: ExportOptions, style: str = "") -> str:
+    """节点语句，style 为追加在最后的属性（同名属性以后出现的为准）"""
+    name = _dot_escape(_label(node, options))
+    if node.external:
+        attributes = [f'label="{name}"', DOT_EXTERNAL_STYLE]
+    elif node.file is None:
+        # 没有声明位置的节点（包级别调用图中的包）
+        attributes = [f'label="{name}"']
+    else:
+        file_path = _dot_escape(node.file)
+        line = node.line if node.line is not None else "?"
+        # 鼠标悬停时显示完整的声明位置（file:line:column，SVG 输出中可见）
+        tooltip = f"{file_path}:{line}"
+        if node.column is not None:
+            tooltip += f":{node.column}"
+        label = f"{name}\\n({file_path}:{line})"
+        attributes = [f'label="{label}"', f'tooltip="{tooltip}"']
+        if node.kind != "function":
+            attributes.append(DOT_TYPE_STYLE)
+    if style:
+        attributes.append(style)
+    return f'  "{_dot_escape(node.id)}" [{", ".join(attributes)}];'
+
+
+@dataclass
+class _MethodRecord:
+    """group_methods 时一个接收者类型的记录节点"""
+
+    id: str
+    package: Optional[str]
+    receiver: str
+    methods: List[Node]
+    # 图中同一类型的类型节点（implements 边的端点），作为记录的标题格子
+    type_node: Optional[Node] = None
+
+    def members(self) -> List[Node]:
+        return ([self.type_node] if self.type_node else []) + self.methods
+
+
+def _method_records(graph: CallGraph) -> List[_MethodRecord]:
+    """按 (包, 接收者类型) 把项目中的方法分组，方法按节点顺序排列"""
+    methods: Dict[Tuple[Optional[str], str], List[Node]] = defaultdict(list)
+    types: Dict[Tuple[Optional[str], str], Node] = {}
+    for node in graph.sorted_nodes():
+        if node.external:
+            continue
+        if node.kind == "function":
+            if node.receiver:
+                methods[(node.package, node.receiver)].append(node)
+        else:
+            types.setdefault((node.package, node.name), node)
+
+    records = []
+    for (package, receiver), nodes in methods.items():
+        type_node = types.get((package, receiver))
+        if type_node:
+            record_id = type_node.id
+        else:
+            record_id = f"record:{package or ''}.{receiver}"
+        records.append(_MethodRecord(record_id, package, receiver, nodes, type_node))
+    return records
+
+
+def _record_escape(text: str) -> str:
+    """转义 record 标签中有特殊含义的字符（以及 DOT 字符串中的引号）"""
+    return re.sub(r'([\\{}|<>"])', r"\\\1", text)
+
+
+def _dot_record(record: _MethodRecord, options: ExportOptions, style: str) -> str:
+    """
+    接收者类型的 Mrecord 节点：第一格为类型名（端口 type），之后每个方法一格
+    （端口 m0、m1...）；rankdir=LR 时各格从上到下排列。
+    方法的标签默认只显示方法名，设置了 options.label 时使用它
+    """
+    fields = [f"<type> {_record_escape(record.receiver)}"]
+    for i, method in enumerate(record.methods):
+        text = options.label(method) if options.label else short_name(method)
+        fields.append(f"<m{i}> {_record_escape(text)}")
+    attributes = [f'label="{"|".join(fields)}"', "shape=Mrecord"]
+    if style:
+        attributes.append(style)
+    return f'  "{_dot_escape(record.id)}" [{", ".join(attributes)}];'
+
+
+def mermaid_aliases(graph: CallGraph) -> Dict[str, str]:
+    """
+    为每个节点分配 Mermaid 可用的节点 ID
+
+    Mermaid 的节点 ID 不能包含点号、括号等字符，这里把名称中的
+    非法字符替换为下划线并加上 fn_ 前缀（避免与 end 等关键字冲突），
+    重名时按稳定的节点顺序追加序号。
+    """
+    aliases = {}
+    used = set()
+    for node in graph.sorted_nodes():
+        base = "fn_" + re.sub(r"\W", "_", node.name, flags=re.ASCII)
+        alias = base
+        suffix = 2
+        while alias in used:
+            alias = f"{base}_{suffix}"
+            suffix += 1
+        used.add(alias)
+        aliases[node.id] = alias
+    return aliases
+
+
+def _mermaid_label(node: Node, options: ExportOptions) -> str:
+    # Mermaid 标签中的双引号和尖括号需要使用实体编码
+    return (
+        _label(node, options)
+        .replace('"', "#quot;")
+        .replace("<", "#lt;")
+        .replace(">", "#gt;")
+    )
+
+
+def to_mermaid(graph: CallGraph, options: Optional[ExportOptions] = None) -> str:
+    """导出为 Mermaid flowchart（可直接嵌入 GitHub Markdown）"""
+    options = options or ExportOptions()
+    aliases = mermaid_aliases(graph)
+    lines = ["flowchart TD"]
+
+    for node in graph.sorted_nodes():
+        label = _mermaid_label(node, options)
+        if node.external:
+            # 外部函数使用圆角节点
+            lines.append(f'    {aliases[node.id]}(["{label}"])')
+        else:
+            lines.append(f'    {aliases[node.id]}["{label}"]')
+
+    for edge in graph.sorted_edges():
+        arrow = MERMAID_EDGE_ARROWS.get(edge.kind, "-->")
+        if edge.weight > 1:
+            arrow += f"|{edge.weight}|"
+        lines.append(f"    {aliases[edge.caller]} {arrow} {aliases[edge.callee]}")
+
+    return "\n".join(lines)
+
+
+def to_json(graph: CallGraph, options: Optional[ExportOptions] = None) -> str:
+    """导出为 JSON（可通过 CallGraph.load_json 重新加载）"""
+    return graph.to_json()
+
+
+# CSV 导出的列
+CSV_COLUMNS = [
+    "caller_pkg",
+    "caller_func",
+    "callee_pkg",
+    "callee_func",
+    "kind",
+    "call_site_file",
+    "call_site_line",
+]
+
+
+def to_csv(graph: CallGraph, options: Optional[ExportOptions] = None) -> str:
+    """
+    导出为 CSV 边列表（第一行为表头），便于用表格、awk、pandas 处理
+
+    每条边一行，同一条边有多处调用时 call_site_file/call_site_line 为第一处调用
+    （按文件和行号排序）的位置；包名为空表示函数没有包信息（其他语言、外部函数）。
+    名称中的逗号、引号等字符由 csv 模块按标准规则转义。
+    """
+    buffer = io.StringIO()
+    writer = csv.writer(buffer, lineterminator="\n")
+    writer.writerow(CSV_COLUMNS)
+    for edge in graph.sorted_edges():
+        caller = graph.nodes[edge.caller]
+        callee = graph.nodes[edge.callee]
+        site = edge.call_sites[0] if edge.call_sites else None
+        writer.writerow(
+            [
+                caller.package or "",
+                caller.name,
+                callee.package or "",
+                callee.name,
+                edge.kind,
+                (site.file or "") if site else "",
+                site.line if site and site.line is not None else "",
+            ]
+        )
+    return buffer.getvalue()
+
+
+# GraphML 的数据字段: (key ID, 所属元素, 属性名, 类型)
+GRAPHML_KEYS = [
+    ("name", "node", "name", "string"),
+    ("package", "node", "package", "string"),
+    ("file", "node", "file", "string"),
+    ("line", "node", "line", "int"),
+    ("external", "node", "external", "boolean"),
+    ("kind", "edge", "kind", "string"),
+    ("weight", "edge", "weight", "int"),
+]
+
+
+def to_graphml(graph: CallGraph, options: Optional[ExportOptions] = None) -> str:
+    """
+    导出为 GraphML（可导入 yEd、Gephi 等工具）
+
+    只使用 GraphML 核心 schema 中的元素，节点 ID 与数据库中的符号 ID 相同
+    （schema 要求是 NMTOKEN，<external> 节点写为 _external_），
+    边按排序后的顺序编号为 e0、e1 ……，同一份数据多次导出的结果完全一致。
+    """
+    lines = ['<?xml version="1.0" encoding="UTF-8"?>']
+    lines.append(
+        '<graphml xmlns="http://graphml.graphdrawing.org/xmlns" '
+        'xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" '
+        'xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns '
+        'http://graphml.graphdrawing.org/xmlns/1.0/graphml.xsd">'
+    )
+    for key_id, domain, attr_name, attr_type in GRAPHML_KEYS:
+        lines.append(
+            f'  <key id="{key_id}" for="{domain}" '
+            f'attr.name="{attr_name}" attr.type="{attr_type}"/>'
+        )
+    lines.append('  <graph id="CallGraph" edgedefault="directed">')
+
+    for node in graph.sorted_nodes():
+        lines.append(f'    <node id="{_graphml_id(node.id)}">')
+        lines.append(_graphml_data("name", node.name))
+        if node.package:
+            lines.append(_graphml_data("package", node.package))
+        if node.file:
+            lines.append(_graphml_data("file", node.file))
+        if node.line is not None:
+            lines.append(_graphml_data("line", str(node.line)))
+        lines.append(_graphml_data("external", str(node.external).lower()))
+        lines.append("    </node>")
+
+    for i, edge in enumerate(graph.sorted_edges()):
+        lines.append(
+            f'    <edge id="e{i}" source="{_graphml_id(edge.caller)}" '
+            f'target="{_graphml_id(edge.callee)}">'
+        )
+        lines.append(_graphml_data("kind", edge.kind))
+        lines.append(_graphml_data("weight", str(edge.weight)))
+        lines.append("    </edge>")
+
+    lines.append("  </graph>")
+    lines.append("</graphml>")
+    return "\n".join(lines)
+
+
+def _graphml_id(node_id: str) -> str:
+    return re.sub(r"[^\w.-]", "_", node_id)
+
+
+def _graphml_data(key: str, value: str) -> str:
+    # 函数名中可能包含 *、[]、<> 等字符（如 <external>），需要转义
+    return f'      <data key="{key}">{escape(value)}</data>'
+
+
+# 没有安装 Graphviz 时，内置布局最多处理的节点数
+SVG_FALLBACK_MAX_NODES = 50
+
+# 内置布局中各类调用边的线型 (颜色, stroke-dasharray)，未列出的为黑色实线
+SVG_EDGE_STYLES = {
+    EdgeKind.INTERFACE: ("blue", "6,3"),
+    EdgeKind.DEFER: ("black", "6,3"),
+    EdgeKind.UNRESOLVED: ("orange", "2,3"),
+    EdgeKind.AMBIGUOUS: ("purple", "6,3"),
+    EdgeKind.IMPLEMENTS: ("gray", "2,3"),
+}
+
+
+def to_svg(graph: CallGraph, options: Optional[ExportOptions] = None) -> str:
+    """
+    导出为 SVG（可以直接在浏览器中打开）
+
+    优先调用 PATH 中 Graphviz 的 dot 命令渲染 to_dot() 的结果；没有安装
+    Graphviz 时，不超过 SVG_FALLBACK_MAX_NODES 个节点的图使用内置的简单分层布局，
+    更大的图抛出 ValueError 提示安装 Graphviz（内置布局无法画出可读的大图）。
+    两种方式生成的节点都带有 file:line 的悬停提示。
+    dot 命令执行失败时抛出 RuntimeError。
+    """
+    dot = shutil.which("dot")
+    if dot is not None:
+        result = subprocess.run(
+            [dot, "-Tsvg"],
+            input=to_dot(graph, options),
+            capture_output=True,
+            text=True,
+            encoding="utf-8",
+        )
+        if result.returncode != 0:
+            raise RuntimeError(f"dot 渲染失败: {result.stderr.strip()}")
+        return result.stdout
+
+    if len(graph.nodes) > SVG_FALLBACK_MAX_NODES:
+        raise ValueError(
+            f"调用图有 {len(graph.nodes)} 个节点，内置布局最多支持 "
+            f"{SVG_FALLBACK_MAX_NODES} 个；请安装 Graphviz（https://graphviz.org/）"
+            "后重试，或者使用 --entry/--depth 缩小导出范围"
+        )
+    return _layered_svg(graph, options or ExportOptions())
+
+
+def _svg_ranks(graph: CallGraph) -> Dict[str, int]:
+    """
+    每个节点所在的列：从没有调用者的节点出发按广度优先搜索计算调用层数，
+    调用环中无法从这些节点到达的部分从其中第一个节点开始继续计算
+    """
+    nodes = graph.sorted_nodes()
+    rank: Dict[str, int] = {}
+    roots = [node.id for node in nodes if not graph.callers_of(node.id)]
+    for start in roots + [node.id for node in nodes]:
+        if start in rank:
+            continue
+        rank[start] = 0
+        queue = deque([start])
+        while queue:
+            current = queue.popleft()
+            for callee in graph.callees_of(current):
+                if callee.id not in rank:
+                    rank[callee.id] = rank[current] + 1
+                    queue.append(callee.id)
+    return rank
+
+
+def _layered_svg(graph: CallGraph, options: ExportOptions) -> str:
+    """内置的从左到右分层布局（不依赖 Graphviz，只适合小图）"""
+    char_width, box_height, row_gap, column_gap, margin = 7, 28, 16, 60, 20
+    labels = {node.id: _label(node, options) for node in graph.nodes.values()}
+
+    rank = _svg_ranks(graph)
+    columns: Dict[int, List[Node]] = defaultdict(list)
+    for node in graph.sorted_nodes():
+        columns[rank[node.id]].append(node)
+
+    # 节点 ID -> (x, y, 宽度)
+    boxes: Dict[str, Tuple[float, float, float]] = {}
+    x = margin
+    height = margin
+    for column in sorted(columns):
+        width = max(len(labels[node.id]) for node in columns[column]) * char_width
+        width += 20
+        y = margin
+        for node in columns[column]:
+            boxes[node.id] = (x, y, width)
+            y += box_height + row_gap
+        height = max(height, y)
+        x += width + column_gap
+    width = x - column_gap + margin
+    # 留出从下方绕回的调用环连线的空间
+    height += column_gap // 2
+
+    lines = [
+        '<?xml version="1.0" encoding="UTF-8"?>',
+        f'<svg xmlns="http://www.w3.org/2000/svg" width="{width}" '
+        f'height="{height}" viewBox="0 0 {width} {height}" '
+        'font-family="Arial" font-size="12">',
+        '  <defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" '
+        'markerWidth="8" markerHeight="8" orient="auto">'
+        '<path d="M0,0 L10,5 L0,10 z"/></marker></defs>',
+    ]
+
+    for edge in graph.sorted_edges():
+        x1, y1, w1 = boxes[edge.caller]
+        x2, y2, w2 = boxes[edge.callee]
+        color, dash = SVG_EDGE_STYLES.get(edge.kind, ("black", ""))
+        attributes = f'fill="none" stroke="{color}" marker-end="url(#arrow)"'
+        if dash:
+            attributes += f' stroke-dasharray="{dash}"'
+        if edge.caller == edge.callee:
+            # 递归调用：从节点上方绕一个圈
+            left, right, top = x1 + w1 - 30, x1 + w1 - 10, y1
+            d = f"M{left},{top} C{left},{top - 20} {right},{top - 20} {right},{top}"
+        elif x2 > x1:
+            start_y, end_y = y1 + box_height / 2, y2 + box_height / 2
+            d = f"M{x1 + w1},{start_y} L{x2},{end_y}"
+        else:
+            # 指向同一列或左侧的调用（调用环）：从下方绕回
+            start_x, end_x = x1 + w1 / 2, x2 + w2 / 2
+            bottom = max(y1, y2) + box_height
+            d = (
+                f"M{start_x},{y1 + box_height} C{start_x},{bottom + 30} "
+                f"{end_x},{bottom + 30} {end_x},{y2 + box_height}"
+            )
+        lines.append(f'  <path d="{d}" {attributes}><title>{edge.kind}</title></path>')
+
+    for node in graph.sorted_nodes():
+        x, y, w = boxes[node.id]
+        if node.file is not None:
+            tooltip = f"{node.file}:{node.line if node.line is not None else '?'}"
+        else:
+            tooltip = node.qualified_name
+        rx = box_height / 2 if node.external else 0
+        stroke = ' stroke-dasharray="4,2"' if node.external else ""
+        lines.append(f"  <g><title>{escape(tooltip)}</title>")
+        lines.append(
+            f'    <rect x="{x}" y="{y}" width="{w}" height="{box_height}" '
+            f'rx="{rx}" fill="white" stroke="black"{stroke}/>'
+        )
+        lines.append(
+            f'    <text x="{x + w / 2}" y="{y + box_height / 2 + 4}" '
+            f'text-anchor="middle">{escape(labels[node.id])}</text>'
+        )
+        lines.append("  </g>")
+
+    lines.append("</svg>")
+    return "\n".join(lines)
+
+
+# 导出格式 -> 导出函数 (graph, options) -> str
+EXPORTERS = {
+    "csv": to_csv,
+    "dot": to_dot,
+    "graphml": to_graphml,
+    "json": to_json,
+    "mermaid": to_mermaid,
+    "svg": to_svg,
+}
@@ -0,0 +1,294 @@
+"""
+Go 构建约束
+按构建环境（GOOS、GOARCH、构建标签）判断一个 Go 文件是否参与构建，
+规则与 go build 相同：文件名后缀（_linux.go、_amd64.go）和文件开头的
+//go:build / // +build 约束都必须满足
+"""
+
+import os
+import platform
+import re
+import sys
+from typing import Iterable, List, Optional, Set, Tuple
+
+# 已知的 GOOS 和 GOARCH（go/build/syslist.go），只有这些名称在文件名中才是约束
+KNOWN_OS = {
+    "aix",
+    "android",
+    "darwin",
+    "dragonfly",
+    "freebsd",
+    "hurd",
+    "illumos",
+    "ios",
+    "js",
+    "linux",
+    "nacl",
+    "netbsd",
+    "openbsd",
+    "plan9",
+    "solaris",
+    "wasip1",
+    "windows",
+    "zos",
+}
+KNOWN_ARCH = {
+    "386",
+    "amd64",
+    "amd64p32",
+    "arm",
+    "armbe",
+    "arm64",
+    "arm64be",
+    "loong64",
+    "mips",
+    "mipsle",
+    "mips64",
+    "mips64le",
+    "mips64p32",
+    "mips64p32le",
+    "ppc",
+    "ppc64",
+    "ppc64le",
+    "riscv",
+    "riscv64",
+    "s390",
+    "s390x",
+    "sparc",
+    "sparc64",
+    "wasm",
+}
+
+# 满足 unix 标签的 GOOS
+UNIX_OS = {
+    "aix",
+    "android",
+    "darwin",
+    "dragonfly",
+    "freebsd",
+    "hurd",
+    "illumos",
+    "ios",
+    "linux",
+    "netbsd",
+    "openbsd",
+    "solaris",
+}
+
+# GOOS 隐含的其他 GOOS 标签（android 也满足 linux 约束，以此类推）
+IMPLIED_OS = {"android": "linux", "illumos": "solaris", "ios": "darwin"}
+
+_PLATFORM_OS = {"win32": "windows", "cygwin": "windows", "darwin": "darwin"}
+_PLATFORM_ARCH = {
+    "x86_64": "amd64",
+    "amd64": "amd64",
+    "aarch64": "arm64",
+    "arm64": "arm64",
+    "i386": "386",
+    "i686": "386",
+    "x86": "386",
+    "armv7l": "arm",
+    "armv6l": "arm",
+    "ppc64le": "ppc64le",
+    "s390x": "s390x",
+    "riscv64": "riscv64",
+    "loongarch64": "loong64",
+}
+
+_TOKEN_RE = re.compile(r"\s*(\|\||&&|!|\(|\)|[\w.]+)")
+
+
+def host_goos() -> str:
+    """当前主机的 GOOS（GOOS 环境变量优先，与 go 命令一致）"""
+    if os.environ.get("GOOS"):
+        return os.environ["GOOS"]
+    for prefix, goos in _PLATFORM_OS.items():
+        if sys.platform.startswith(prefix):
+            return goos
+    name = re.sub(r"\d+$", "", sys.platform)
+    return name if name in KNOWN_OS else "linux"
+
+
+def host_goarch() -> str:
+    """当前主机的 GOARCH（GOARCH 环境变量优先）"""
+    if os.environ.get("GOARCH"):
+        return os.environ["GOARCH"]
+    return _PLATFORM_ARCH.get(platform.machine().lower(), "amd64")
+
+
+class BuildContext:
+    """
+    构建环境
+
+    满足的标签包括 GOOS、GOARCH、GOOS 隐含的标签（android 隐含 linux 等）、
+    unix（类 Unix 系统）、gc 编译器、所有 go1.N 版本标签，以及额外指定的
+    构建标签。cgo 等其他标签需要通过 tags 显式指定。
+    """
+
+    def __init__(
+        self,
+        goos: Optional[str] = None,
+        goarch: Optional[str] = None,
+        tags: Iterable[str] = (),
+    ):
+        self.goos = goos or host_goos()
+        self.goarch = goarch or host_goarch()
+        self.tags: Set[str] = {self.goos, self.goarch, "gc", *tags}
+        if self.goos in IMPLIED_OS:
+            self.tags.add(IMPLIED_OS[self.goos])
+        if self.goos in UNIX_OS:
+            self.tags.add("unix")
+
+    @classmethod
+    def from_options(cls, options) -> "BuildContext":
+        return cls(options.goos, options.goarch, options.build_tags)
+
+    def satisfied(self, tag: str) -> bool:
+        # 假设使用最新的工具链，所有 go1.N 版本标签都满足
+        return tag in self.tags or re.fullmatch(r"go1\.\d+", tag) is not None
+
+    def matches_filename(self, file_path: str) -> bool:
+        """文件名中的 _GOOS、_GOARCH、_GOOS_GOARCH 后缀（可以再跟 _test）"""
+        name = os.path.basename(file_path).split(".", 1)[0]
+        index = name.find("_")
+        if index < 0:
+            return True
+        # 第一个下划线之前的部分不算约束（linux.go 不是约束）
+        parts = name[index:].split("_")
+        if parts[-1] == "test":
+            parts = parts[:-1]
+        if len(parts) >= 2 and parts[-2] in KNOWN_OS and parts[-1] in KNOWN_ARCH:
+            return self.satisfied(parts[-2]) and self.satisfied(parts[-1])
+        if parts and (parts[-1] in KNOWN_OS or parts[-1] in KNOWN_ARCH):
+            return self.satisfied(parts[-1])
+        return True
+
+    def matches_constraints(
+        self, go_build: Optional[str], plus_build: List[str]
+    ) -> bool:
+        """
+        文件开头的构建约束是否满足
+
+        有 //go:build 时只使用它；否则所有 // +build 行都必须满足，
+        每行中空格分隔的各项满足一项即可，逗号分隔的各项必须都满足。
+        无法解析的约束表达式按满足处理（go build 会报错，这里不中断分析）。
+        """
+        if go_build is not None:
+            try:
+                return _evaluate(_parse_expression(go_build), self.satisfied)
+            except ValueError:
+                return True
+        return all(
+            any(
+                all(self._term(term) for term in option.split(","))
+                for option in line.split()
+            )
+            for line in plus_build
+        )
+
+    def _term(self, term: str) -> bool:
+        if term.startswith("!"):
+            return not self.satisfied(term[1:])
+        return self.satisfied(term)
+
+    def matches_file(self, file_path: str) -> bool:
+        """Go 文件是否参与构建（先检查文件名，再读取文件开头的约束）"""
+        if not self.matches_filename(file_path):
+            return False
+        try:
+            go_build, plus_build = read_constraints(file_path)
+        except OSError:
+            # 无法读取的文件交给解析器报告错误
+            return True
+        return self.matches_constraints(go_build, plus_build)
+
+
+def read_constraints(file_path: str) -> Tuple[Optional[str], List[str]]:
+    """
+    读取文件开头的构建约束，返回 (//go:build 表达式, // +build 行列表)
+
+    约束只能出现在 package 子句之前，前面只能有空行和其他行注释
+    """
+    go_build = None
+    plus_build = []
+    with open(file_path, "r", encoding="utf-8", errors="ignore") as f:
+        for line in f:
+            line = line.strip()
+            if not line:
+                continue
+            if not line.startswith("//"):
+                break
+            if line.startswith("//go:build") and line[10:11] in ("", " ", "\t"):
+                if go_build is None:
+                    go_build = line[len("//go:build") :].strip()
+            else:
+                match = re.match(r"//\s*\+build(\s|$)", line)
+                if match:
+                    plus_build.append(line[match.end() :].strip())
+    return go_build, plus_build
+
+
+def _parse_expression(text: str):
+    """
+    解析 //go:build 表达式，返回嵌套的元组：
+    ("tag", 名称) / ("not", x) / ("and", x, y) / ("or", x, y)
+    """
+    tokens = []
+    position = 0
+    text = text.rstrip()
+    while position < len(text):
+        match = _TOKEN_RE.match(text, position)
+        if not match:
+            raise ValueError(f"无效的构建约束: {text}")
+        tokens.append(match.group(1))
+        position = match.end()
+
+    def parse_or(i):
+        left, i = parse_and(i)
+        while i < len(tokens) and tokens[i] == "||":
+            right, i = parse_and(i + 1)
+            left = ("or", left, right)
+        return left, i
+
+    def parse_and(i):
+        left, i = parse_not(i)
+        while i < len(tokens) and tokens[i] == "&&":
+            right, i = parse_not(i + 1)
+            left = ("and", left, right)
+        return left, i
+
+    def parse_not(i):
+        if i < len(tokens) and tokens[i] == "!":
+            operand, i = parse_not(i + 1)
+            return ("not", operand), i
+        return parse_atom(i)
+
+    def parse_atom(i):
+        if i >= len(tokens):
+            raise ValueError(f"无效的构建约束: {text}")
+        if tokens[i] == "(":
+            expression, i = parse_or(i + 1)
+            if i >= len(tokens) or tokens[i] != ")":
+                raise ValueError(f"无效的构建约束: {text}")
+            return expression, i + 1
+        if tokens[i] in ("||", "&&", ")"):
+            raise ValueError(f"无效的构建约束: {text}")
+        return ("tag", tokens[i]), i + 1
+
+    expression, end = parse_or(0)
+    if end != len(tokens):
+        raise ValueError(f"无效的构建约束: {text}")
+    return expression
+
+
+def _evaluate(expression, satisfied) -> bool:
+    op = expression[0]
+    if op == "tag":
+        return satisfied(expression[1])
+    if op == "not":
+        return not _evaluate(expression[1], satisfied)
+    if op == "and":
+        return _evaluate(expression[1], satisfied) and _evaluate(
+            expression[2], satisfied
+        )
+    return _evaluate(expression[1], satisfied) or _evaluate(expression[2], satisfied)
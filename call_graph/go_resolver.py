@@ -0,0 +1,1248 @@
+"""
+Go 语言调用解析
+在 tree-sitter 语法树上做轻量的静态类型推断，把方法调用和接口调用
+解析到项目中具体的函数声明上
+"""
+
+import os
+import re
+from functools import lru_cache
+from typing import Any, Dict, List, Optional, Set, Tuple
+
+# 支持相对导入和直接运行
+try:
+    from .graph import EdgeKind
+except ImportError:
+    from graph import EdgeKind
+
+# 类型引用: (包标识, 类型名)，预声明类型的包标识为空字符串
+TypeRef = Tuple[str, str]
+
+# 预声明的类型名，出现在调用位置时是类型转换而不是函数调用
+GO_PREDECLARED_TYPES = {
+    "any",
+    "bool",
+    "byte",
+    "comparable",
+    "complex64",
+    "complex128",
+    "error",
+    "float32",
+    "float64",
+    "int",
+    "int8",
+    "int16",
+    "int32",
+    "int64",
+    "rune",
+    "string",
+    "uint",
+    "uint8",
+    "uint16",
+    "uint32",
+    "uint64",
+    "uintptr",
+}
+
+# 常用标准库接口的方法集，这些接口不在分析范围内，只按方法名匹配实现
+WELL_KNOWN_INTERFACES: Dict[TypeRef, Set[str]] = {
+    ("", "error"): {"Error"},
+    ("fmt", "Stringer"): {"String"},
+    ("io", "Reader"): {"Read"},
+    ("io", "Writer"): {"Write"},
+    ("io", "Closer"): {"Close"},
+    ("io", "ReadWriter"): {"Read", "Write"},
+    ("io", "ReadCloser"): {"Read", "Close"},
+    ("io", "WriteCloser"): {"Write", "Close"},
+    ("io", "ReadWriteCloser"): {"Read", "Write", "Close"},
+    ("sort", "Interface"): {"Len", "Less", "Swap"},
+    ("net/http", "Handler"): {"ServeHTTP"},
+}
+
+
+class FuncValue:
+    """
+    局部变量中保存的已知函数值（如 f := func() {...}、f := user.Greet）
+
+    与类型引用一起保存在变量环境中，调用该变量时解析到这些函数；
+    kind 是调用该变量时的边类型（接口方法值为 interface）
+    """
+
+    def __init__(self, targets: List[Dict[str, Any]], kind: str = EdgeKind.DIRECT):
+        self.targets = targets
+        self.kind = kind
+
+
+class ExternalValue:
+    """
+    来自项目外部的值（外部包中的变量、外部函数的返回值等）
+
+    类型未知，但不会是项目中声明的类型，在它上面调用方法时不按方法名
+    猜测项目中的候选（见 EdgeKind.AMBIGUOUS）
+    """
+
+
+EXTERNAL_VALUE = ExternalValue()
+
+
+# 调用位置上带单个类型实参的泛型函数 F[int](...) 被 tree-sitter 解析为索引表达式
+# （多个类型实参时解析为带 type_arguments 字段的普通调用）
+GENERIC_INSTANTIATIONS = ("index_expression",)
+
+_NAMED_TYPE_RE = re.compile(r"^([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)?)(\[.*\])?$")
+_TYPE_KEYWORDS = {"map", "chan", "func", "struct", "interface"}
+
+
+@lru_cache(maxsize=None)
+def find_go_module(directory: str) -> Optional[Tuple[str, str]]:
+    """向上查找 go.mod，返回 (模块路径, 模块根目录)"""
+    current = os.path.abspath(directory)
+    while True:
+        go_mod = os.path.join(current, "go.mod")
+        if os.path.isfile(go_mod):
+            with open(go_mod, "r", encoding="utf-8", errors="ignore") as f:
+                for line in f:
+                    match = re.match(r"\s*module\s+(\S+)", line)
+                    if match:
+                        return match.group(1).strip('"'), current
+            return None
+        parent = os.path.dirname(current)
+        if parent == current:
+            return None
+        current = parent
+
+
+def go_package_id(
+    file_path: str,
+    package_name: str,
+    package_paths: Optional[Dict[str, str]] = None,
+) -> str:
+    """
+    计算文件所属包的标识
+
+    package_paths（目录 -> 导入路径，来自 go list）中有文件所在的目录时使用其中的
+    导入路径；否则找到 go.mod 时使用完整的导入路径（模块路径 + 相对目录），
+    都没有时退化为 package 子句中的包名。测试文件中的外部测试包
+    （package foo_test）与被测试的包是不同的包，导入路径加上 _test 后缀。
+    """
+    directory = os.path.dirname(os.path.abspath(file_path))
+    if package_paths and directory in package_paths:
+        import_path = package_paths[directory]
+    else:
+        module = find_go_module(directory)
+        if not module:
+            return package_name
+        module_path, module_dir = module
+        relative = os.path.relpath(directory, module_dir).replace(os.sep, "/")
+        import_path = (
+            module_path if relative == "." else f"{module_path}/{relative}"
+        )
+    if file_path.endswith("_test.go") and package_name.endswith("_test"):
+        return f"{import_path}_test"
+    return import_path
+
+
+def default_import_alias(import_path: str) -> str:
+    """根据导入路径推测包的默认引用名（如 gopkg.in/yaml.v3 -> yaml）"""
+    parts = import_path.split("/")
+    name = parts[-1]
+    if re.fullmatch(r"v\d+", name) and len(parts) > 1:
+        name = parts[-2]
+    name = re.sub(r"\.v\d+$", "", name)
+    if name.startswith("go-"):
+        name = name[3:]
+    return name
+
+
+def named_type(type_text: str) -> Optional[str]:
+    """
+    从类型文本中提取命名类型（去掉指针、括号和泛型实参）
+
+    例如 "*User" -> "User"，"pkg.List[int]" -> "pkg.List"，
+    切片、映射、函数等非命名类型返回 None
+    """
+    text = type_text.strip()
+    while text.startswith("*") or (text.startswith("(") and text.endswith(")")):
+        text = text[1:].strip() if text.startswith("*") else text[1:-1].strip()
+
+    match = _NAMED_TYPE_RE.match(text)
+    if not match or match.group(1) in _TYPE_KEYWORDS:
+        return None
+    return match.group(1)
+
+
+def type_parameter_names(node, source_code: bytes) -> Set[str]:
+    """
+    声明中的类型参数名
+
+    包括函数/类型自身的类型参数列表（func Map[T, U any]），
+    以及泛型类型方法在接收者中声明的类型参数（func (s *Stack[T]) Push）
+    """
+    names: Set[str] = set()
+    type_parameters = node.child_by_field_name("type_parameters")
+    if type_parameters is not None:
+        for declaration in type_parameters.named_children:
+            if declaration.type == "type_parameter_declaration":
+                for name_node in declaration.children_by_field_name("name"):
+                    names.add(node_text(name_node, source_code))
+
+    receiver = node.child_by_field_name("receiver")
+    if receiver is not None:
+        for param in receiver.named_children:
+            type_node = param.child_by_field_name("type")
+            if type_node is None:
+                continue
+            match = re.search(r"\[(.*)\]\s*$", node_text(type_node, source_code))
+            if match:
+                names.update(n.strip() for n in match.group(1).split(",") if n.strip())
+    return names
+
+
+def node_text(node, source_code: bytes) -> str:
+    """获取节点的文本内容"""
+    return source_code[node.start_byte : node.end_byte].decode(
+        "utf-8", errors="ignore"
+    )
+
+
+class GoFileContext:
+    """单个 Go 文件的包信息和导入表"""
+
+    def __init__(
+        self,
+        root,
+        source_code: bytes,
+        file_path: str,
+        package_paths: Optional[Dict[str, str]] = None,
+    ):
+        self.source_code = source_code
+        self.package_name = ""
+        # 引用名 -> 导入路径
+        self.imports: Dict[str, str] = {}
+
+        for node in root.children:
+            if node.type == "package_clause":
+                for child in node.named_children:
+                    if child.type == "package_identifier":
+                        self.package_name = self.text(child)
+            elif node.type == "import_declaration":
+                self._collect_imports(node)
+
+        self.package = go_package_id(file_path, self.package_name, package_paths)
+
+    def text(self, node) -> str:
+        return node_text(node, self.source_code)
+
+    def _collect_imports(self, node):
+        for child in node.named_children:
+            if child.type == "import_spec_list":
+                self._collect_imports(child)
+            elif child.type == "import_spec":
+                path_node = child.child_by_field_name("path")
+                if path_node is None:
+                    continue
+                path = self.text(path_node).strip('"`')
+                name_node = child.child_by_field_name("name")
+                if name_node is not None:
+                    alias = self.text(name_node)
+                    # 匿名导入和点导入不会引入包引用名
+                    if alias in ("_", "."):
+                        continue
+                else:
+                    alias = default_import_alias(path)
+                self.imports[alias] = path
+
+    def type_ref(
+        self, type_text: str, type_params: Optional[Set[str]] = None
+    ) -> Optional[TypeRef]:
+        """
+        把文件中出现的类型文本解析为类型引用
+
+        type_params 中的名称是当前声明的类型参数，不是具体类型，返回 None
+        """
+        name = named_type(type_text)
+        if not name or (type_params and name in type_params):
+            return None
+        if "." in name:
+            alias, type_name = name.split(".", 1)
+            path = self.imports.get(alias)
+            return (path, type_name) if path else None
+        if name in GO_PREDECLARED_TYPES:
+            return ("", name)
+        return (self.package, name)
+
+
+class GoIndex:
+    """项目中所有 Go 符号的索引（跨文件、跨包）"""
+
+    # 最近一次构建的索引：(符号列表, 符号个数, 索引)
+    _cached: Optional[Tuple[List[Dict[str, Any]], int, "GoIndex"]] = None
+
+    @classmethod
+    def for_symbols(cls, symbols: List[Dict[str, Any]]) -> "GoIndex":
+        """
+        获取符号列表对应的索引
+
+        第二遍扫描时每个文件都使用同一个全部符号列表，
+        这里复用上一次构建的索引，避免每个文件都重新索引整个项目
+        """
+        cached = cls._cached
+        if cached is not None and cached[0] is symbols and cached[1] == len(symbols):
+            return cached[2]
+        index = cls(symbols)
+        cls._cached = (symbols, len(symbols), index)
+        return index
+
+    def __init__(self, symbols: List[Dict[str, Any]]):
+        self.packages: Set[str] = set()
+        # (包, 函数名) -> 函数符号
+        self.functions: Dict[Tuple[str, str], Dict[str, Any]] = {}
+        # (包, 类型名) -> {方法名: 方法符号}
+        self.methods: Dict[TypeRef, Dict[str, Dict[str, Any]]] = {}
+        # (包, 类型名) -> 类型符号
+        self.types: Dict[TypeRef, Dict[str, Any]] = {}
+        # (包, 变量名) -> 包级变量符号
+        self.variables: Dict[Tuple[str, str], Dict[str, Any]] = {}
+        # 在函数中被重新赋值过的包级变量 (包, 变量名)
+        self.reassigned: Set[Tuple[str, str]] = set()
+        # (包, 类型名, 字段名) -> 项目中给该字段赋的所有值（见 GoParser._field_assignments）
+        self.field_values: Dict[Tuple[str, str, str], List[list]] = {}
+        self._implementations: Dict[TypeRef, List[TypeRef]] = {}
+        self._method_sets: Dict[TypeRef, Dict[str, Dict[str, Any]]] = {}
+        # 方法名 -> 所有类型上同名的方法符号，见 methods_named()
+        self._methods_by_name: Optional[Dict[str, List[Dict[str, Any]]]] = None
+
+        for symbol in symbols:
+            if symbol.get("language") != "go" or "package" not in symbol:
+                continue
+            package = symbol["package"]
+            self.packages.add(package)
+
+            if symbol["kind"] == "function":
+                receiver = symbol.get("receiver")
+                if receiver:
+                    method_name = symbol["name"].rsplit(".", 1)[-1]
+                    # 重复声明时保留第一个
+                    self.methods.setdefault((package, receiver), {}).setdefault(
+                        method_name, symbol
+                    )
+                else:
+                    self.functions.setdefault((package, symbol["name"]), symbol)
+                for assigned in symbol.get("extras", {}).get("assigns", []):
+                    self.reassigned.add(tuple(assigned))
+                for field_assign in symbol.get("extras", {}).get("field_assigns", []):
+                    type_package, type_name, field, value = field_assign
+                    key = (type_package, type_name, field)
+                    self.field_values.setdefault(key, []).append(value)
+            elif symbol["kind"] == "variable":
+                self.variables.setdefault((package, symbol["name"]), symbol)
+            else:
+                self.types.setdefault((package, symbol["name"]), symbol)
+
+        # pkg.Var = ... 记录的是导入路径，映射到已分析的包标识
+        self.reassigned = {
+            (self.canonical_package(package), name)
+            for package, name in self.reassigned
+        }
+        field_values: Dict[Tuple[str, str, str], List[list]] = {}
+        for (package, type_name, field), values in self.field_values.items():
+            key = (self.canonical_package(package), type_name, field)
+            field_values.setdefault(key, []).extend(values)
+        self.field_values = field_values
+
+    def canonical_package(self, package: str) -> str:
+        """
+        把导入路径映射到已分析的包标识
+
+        没有 go.mod 时包标识是包名，这时按导入路径的最后一段匹配
+        """
+        if package in self.packages:
+            return package
+        alias = default_import_alias(package)
+        if alias in self.packages:
+            return alias
+        return package
+
+    def canonical(self, ref: Optional[TypeRef]) -> Optional[TypeRef]:
+        if ref is None:
+            return None
+        return (self.canonical_package(ref[0]), ref[1])
+
+    def is_interface(self, ref: TypeRef) -> bool:
+        symbol = self.types.get(ref)
+        if symbol is not None:
+            return symbol["kind"] == "interface"
+        return ref in WELL_KNOWN_INTERFACES
+
+    def interface_methods(
+        self, ref: TypeRef, seen: Optional[Set[TypeRef]] = None
+    ) -> Dict[str, Optional[Tuple[int, int]]]:
+        """
+        接口的完整方法集（包含嵌入的接口）
+
+        返回 {方法名: (参数个数, 返回值个数)}，签名未知时为 None
+        """
+        if ref in WELL_KNOWN_INTERFACES:
+            return {name: None for name in WELL_KNOWN_INTERFACES[ref]}
+
+        symbol = self.types.get(ref)
+        if symbol is None or symbol["kind"] != "interface":
+            return {}
+
+        seen = seen if seen is not None else set()
+        if ref in seen:
+            return {}
+        seen.add(ref)
+
+        extras = symbol.get("extras", {})
+        methods = {
+            name: tuple(signature)
+            for name, signature in extras.get("methods", {}).items()
+        }
+        for embedded in extras.get("embedded", []):
+            embedded_ref = self.canonical(tuple(embedded))
+            for name, signature in self.interface_methods(embedded_ref, seen).items():
+                methods.setdefault(name, signature)
+        return methods
+
+    def method_set(self, ref: TypeRef) -> Dict[str, Dict[str, Any]]:
+        """
+        具体类型的方法集 {方法名: 方法符号}
+
+        包含类型上声明的方法，以及通过嵌入字段提升的具体方法
+        （嵌入接口提升的方法没有具体声明，不包含在内）
+        """
+        if ref in self._method_sets:
+            return self._method_sets[ref]
+
+        result = dict(self.methods.get(ref, {}))
+        for name in sorted(self._embedded_method_names(ref)):
+            if name in result:
+                continue
+            member = self.select(ref, name)
+            if member and member[0] == "method" and not self.is_interface(member[1]):
+                result[name] = self.methods[member[1]][name]
+
+        self._method_sets[ref] = result
+        return result
+
+    def embedded_types(self, ref: TypeRef) -> List[TypeRef]:
+        """结构体的嵌入字段类型（* 嵌入指针与值嵌入同样处理）"""
+        symbol = self.types.get(ref)
+        if symbol is None or symbol["kind"] != "struct":
+            return []
+        return [
+            self.canonical(tuple(field["ref"]))
+            for field in symbol.get("extras", {}).get("fields", {}).values()
+            if field.get("embedded") and field.get("ref")
+        ]
+
+    def _embedded_method_names(self, ref: TypeRef) -> Set[str]:
+        names: Set[str] = set()
+        seen = {ref}
+        pending = self.embedded_types(ref)
+        while pending:
+            current = pending.pop()
+            if current in seen:
+                continue
+            seen.add(current)
+            names.update(self.methods.get(current, {}))
+            pending.extend(self.embedded_types(current))
+        return names
+
+    def select(self, ref: TypeRef, name: str) -> Optional[Tuple[str, TypeRef]]:
+        """
+        按 Go 的选择器规则解析 x.name
+
+        从类型本身（深度 0）开始逐层查找嵌入字段，返回最浅深度上
+        唯一的字段或方法：("field" | "method", 声明它的类型)。
+        同一深度上有多个同名字段/方法（包括经由不同路径嵌入同一类型）
+        时选择器有歧义，与找不到一样返回 None。
+        """
+        level = [ref]
+        visited: Set[TypeRef] = set()
+        while level:
+            found = []
+            next_level = []
+            for current in level:
+                if self.is_interface(current):
+                    # 嵌入的接口只提供方法
+                    if name in self.interface_methods(current):
+                        found.append(("method", current))
+                    continue
+                if name in self.methods.get(current, {}):
+                    found.append(("method", current))
+                symbol = self.types.get(current)
+                if symbol is not None and name in symbol.get("extras", {}).get(
+                    "fields", {}
+                ):
+                    found.append(("field", current))
+                next_level.extend(self.embedded_types(current))
+
+            if len(found) == 1:
+                return found[0]
+            if found:
+                return None
+
+            visited.update(level)
+            level = [t for t in next_level if t not in visited]
+        return None
+
+    def implementations(self, interface: TypeRef) -> List[TypeRef]:
+        """项目中所有满足该接口的具体类型（按方法名和参数/返回值个数匹配）"""
+        if interface in self._implementations:
+            return self._implementations[interface]
+
+        required = self.interface_methods(interface)
+        result = []
+        if required:
+            for ref in sorted(self.methods):
+                if self.is_interface(ref):
+                    continue
+                method_set = self.method_set(ref)
+                if all(
+                    name in method_set
+                    and self._signature_matches(method_set[name], signature)
+                    for name, signature in required.items()
+                ):
+                    result.append(ref)
+
+        self._implementations[interface] = result
+        return result
+
+    @staticmethod
+    def _signature_matches(
+        symbol: Dict[str, Any], signature: Optional[Tuple[int, int]]
+    ) -> bool:
+        if signature is None:
+            return True
+        extras = symbol.get("extras", {})
+        return (extras.get("params"), len(extras.get("results", []))) == signature
+
+    def lookup_method(
+        self, ref: TypeRef, name: str, resolve_interfaces: bool = True
+    ) -> Tuple[List[Dict[str, Any]], str]:
+        """
+        在静态类型上查找方法
+
+        返回 (候选方法符号列表, 边类型)。静态类型为接口时，
+        候选是所有实现该接口的具体类型上的同名方法。
+        """
+        ref = self.canonical(ref)
+        if self.is_interface(ref):
+            if not resolve_interfaces:
+                return [], EdgeKind.INTERFACE
+            # 多个实现可能共享同一个提升的方法，按声明去重
+            targets = {}
+            for impl in self.implementations(ref):
+                symbol = self.method_set(impl).get(name)
+                if symbol is not None:
+                    targets.setdefault(symbol["id"], symbol)
+            return list(targets.values()), EdgeKind.INTERFACE
+
+        member = self.select(ref, name)
+        if member is None or member[0] != "method":
+            return [], EdgeKind.DIRECT
+        owner = member[1]
+        if owner != ref:
+            # 通过嵌入字段提升的方法，边指向方法实际声明的位置
+            return self.lookup_method(owner, name, resolve_interfaces)
+        return [self.methods[ref][name]], EdgeKind.DIRECT
+
+    def methods_named(self, name: str, package: str) -> List[Dict[str, Any]]:
+        """
+        项目中所有名为 name 的方法（按包和类型名排序），在 package 中调用；
+        未导出的方法只能在声明它的包中调用，只返回 package 中的
+        """
+        if self._methods_by_name is None:
+            self._methods_by_name = {}
+            for ref in sorted(self.methods):
+                for method_name, symbol in self.methods[ref].items():
+                    self._methods_by_name.setdefault(method_name, []).append(symbol)
+        candidates = self._methods_by_name.get(name, [])
+        if not name[:1].isupper():
+            candidates = [s for s in candidates if s["package"] == package]
+        return candidates
+
+    def field_type(self, ref: TypeRef, name: str) -> Optional[TypeRef]:
+        """结构体字段的类型（包括通过嵌入字段提升的字段）"""
+        ref = self.canonical(ref)
+        member = self.select(ref, name)
+        if member is None or member[0] != "field":
+            return None
+        symbol = self.types[member[1]]
+        field = symbol.get("extras", {}).get("fields", {}).get(name)
+        if field and field.get("ref"):
+            return self.canonical(tuple(field["ref"]))
+        return None
+
+    def func_field(self, ref: TypeRef, name: str) -> Optional[TypeRef]:
+        """
+        x.name 是函数类型的结构体字段（func(...) 或底层类型是函数的命名类型）时
+        返回声明该字段的类型，否则返回 None
+        """
+        member = self.select(self.canonical(ref), name)
+        if member is None or member[0] != "field":
+            return None
+        field = self.types[member[1]].get("extras", {}).get("fields", {}).get(name)
+        if not field or field.get("embedded"):
+            return None
+        type_text = field.get("type", "").lstrip("*").strip()
+        if type_text.startswith("func"):
+            return member[1]
+        field_ref = self.canonical(tuple(field["ref"])) if field.get("ref") else None
+        named = self.types.get(field_ref) if field_ref else None
+        if named is not None and named["kind"] == "type":
+            underlying = named.get("extras", {}).get("underlying", "")
+            if underlying.startswith("func"):
+                return member[1]
+        return None
+
+    def field_targets(self, ref: TypeRef, name: str) -> List[Dict[str, Any]]:
+        """
+        调用函数类型的字段 ref.name 时的目标函数
+
+        项目中给该字段赋的每个值（构造函数中的 T{name: f}、x.name = x.Method 等）
+        都是已知的函数或方法时返回这些函数；没有找到赋值，或者有任何一处赋值
+        无法确定是哪个函数时返回空列表
+        """
+        values = self.field_values.get((ref[0], ref[1], name))
+        if not values:
+            return []
+        targets: Dict[str, Dict[str, Any]] = {}
+        for value in values:
+            resolved: List[Dict[str, Any]] = []
+            if value[0] == "func":
+                package = self.canonical_package(value[1])
+                function = self.functions.get((package, value[2]))
+                if function:
+                    resolved = [function]
+                else:
+                    resolved = self.variable_targets(package, value[2]) or []
+            elif value[0] == "method":
+                owner = self.canonical((value[1], value[2]))
+                if not self.is_interface(owner):
+                    resolved, _ = self.lookup_method(owner, value[3])
+            if not resolved:
+                return []
+            for symbol in resolved:
+                targets.setdefault(symbol["id"], symbol)
+        return list(targets.values())
+
+    def variable_targets(
+        self, package: str, name: str
+    ) -> Optional[List[Dict[str, Any]]]:
+        """
+        调用包级函数变量时的目标函数
+
+        不是包级变量时返回 None；变量的初始值是函数名并且没有在任何函数中
+        被重新赋值时返回该函数，初始值是另一个这样的变量时继续查找；
+        其他情况（保存在 map 中、初始值是匿名函数、被重新赋值等）返回空列表
+        """
+        seen = set()
+        key = (package, name)
+        while key in self.variables and key not in seen:
+            seen.add(key)
+            ref = self.variables[key].get("extras", {}).get("func_ref")
+            if not ref or key in self.reassigned:
+                return []
+            key = (self.canonical_package(ref[0]), ref[1])
+            function = self.functions.get(key)
+            if function:
+                return [function]
+        return [] if seen else None
+
+    def result_types(self, symbol: Dict[str, Any]) -> List[Optional[TypeRef]]:
+        """函数返回值的类型列表"""
+        return [
+            self.canonical(tuple(ref)) if ref else None
+            for ref in symbol.get("extras", {}).get("results", [])
+        ]
+
+    def implements_pairs(self) -> List[Tuple[Dict[str, Any], Dict[str, Any]]]:
+        """
+        项目中的具体类型和它满足的接口: [(类型符号, 接口符号)]
+
+        只考虑项目中声明的接口和类型（不包含 error、fmt.Stringer 等标准库接口），
+        满足的规则与接口调用的解析相同，见 implementations()
+        """
+        pairs = []
+        for ref, interface in sorted(self.types.items()):
+            if interface["kind"] != "interface":
+                continue
+            for impl in self.implementations(ref):
+                symbol = self.types.get(impl)
+                if symbol is not None:
+                    pairs.append((symbol, interface))
+        return pairs
+
+
+class GoCallExtractor:
+    """从单个 Go 文件中提取调用关系"""
+
+    def __init__(
+        self,
+        parser,
+        file_path: str,
+        root,
+        source_code: bytes,
+        symbols: List[Dict[str, Any]],
+    ):
+        self.parser = parser
+        self.options = parser.options
+        self.file_path = file_path
+        self.root = root
+        self.context = GoFileContext(
+            root, source_code, file_path, self.options.go_package_paths
+        )
+        self.index = GoIndex.for_symbols(symbols)
+        # 本文件中函数声明的起始字节 -> 函数符号
+        self.symbols_at = {
+            symbol["start_byte"]: symbol
+            for symbol in symbols
+            if symbol["file"] == file_path and symbol["kind"] == "function"
+        }
+        self.calls: List[Dict[str, Any]] = []
+        # 被立即调用的匿名函数（func() {...}()）的起始字节
+        self._invoked_literals: Set[int] = set()
+        # go / defer 语句中的调用表达式 (起始字节, 结束字节) -> 边类型
+        self._statement_kinds: Dict[Tuple[int, int], str] = {}
+        # 当前函数声明的类型参数名（泛型函数）
+        self._type_params: Set[str] = set()
+
+    def text(self, node) -> str:
+        return self.context.text(node)
+
+    def extract(self) -> List[Dict[str, Any]]:
+        for node in self.root.children:
+            if node.type not in ("function_declaration", "method_declaration"):
+                continue
+            caller = self.symbols_at.get(node.start_byte)
+            body = node.child_by_field_name("body")
+            if caller is None or body is None:
+                continue
+            self._type_params = type_parameter_names(node, self.context.source_code)
+
+            # 变量名 -> 类型引用 / FuncValue / EXTERNAL_VALUE / None（未知）
+            env: Dict[str, Any] = {}
+            self._bind_params(node.child_by_field_name("receiver"), env)
+            self._bind_params(node.child_by_field_name("parameters"), env)
+            self._walk(body, caller, env)
+
+        return self.calls
+
+    # ---- 遍历 ----
+
+    def _walk(self, node, caller: Dict[str, Any], env: Dict[str, Any]):
+        """
+        遍历 node 的所有子节点（不区分语句类型），if/for/range/switch/select、
+        带标签的语句等任意嵌套位置中的调用表达式都会被访问；只有匿名函数
+        改变调用的归属
+        """
+        if node.type == "func_literal":
+            self._walk_closure(node, caller, env)
+            return
+
+        if node.type in ("go_statement", "defer_statement"):
+            kind = EdgeKind.GO if node.type == "go_statement" else EdgeKind.DEFER
+            for child in node.named_children:
+                if child.type == "call_expression":
+                    self._statement_kinds[(child.start_byte, child.end_byte)] = kind
+
+        if node.type == "call_expression":
+            self._handle_call(node, caller, env)
+
+        for child in node.children:
+            self._walk(child, caller, env)
+
+        # 先处理右侧表达式中的调用，再绑定左侧变量
+        if node.type == "short_var_declaration":
+            self._bind_assignment(
+                node.child_by_field_name("left"),
+                node.child_by_field_name("right"),
+                env,
+            )
+        elif node.type == "var_spec":
+            self._bind_var_spec(node, env)
+        elif node.type == "assignment_statement":
+            self._rebind_func_values(node, env)
+
+    def _walk_closure(self, node, caller: Dict[str, Any], env: Dict[str, Any]):
+        """匿名函数：从外层函数连一条边到匿名函数，函数体内的调用归属于匿名函数"""
+        closure = self.symbols_at.get(node.start_byte)
+        body = node.child_by_field_name("body")
+        if closure is None or body is None:
+            for child in node.children:
+                self._walk(child, caller, env)
+            return
+
+        # 立即调用的匿名函数已经有调用边，不再重复添加定义处的边
+        if node.start_byte not in self._invoked_literals:
+            self._add_call(
+                caller,
+                node,
+                closure["id"],
+                closure["name"],
+                closure["file"],
+                EdgeKind.DIRECT,
+            )
+        # 匿名函数可以访问外层变量，但在其内部声明的变量不影响外层
+        inner_env = dict(env)
+        self._bind_params(node.child_by_field_name("parameters"), inner_env)
+        self._walk(body, closure, inner_env)
+
+    def _handle_call(self, node, caller: Dict[str, Any], env: Dict[str, Any]):
+        function = node.child_by_field_name("function")
+        if function is None or self._is_conversion(function):
+            return
+
+        targets, kind, name = self._resolve(function, env)
+        # go/defer 只改变调用的执行方式，目标仍按普通调用解析；
+        # 边类型优先标记为 go/defer（包括通过接口调用的情况），
+        # 但 ambiguous 保持不变，目标不确定比执行方式更需要被注意
+        statement_kind = self._statement_kinds.get((node.start_byte, node.end_byte))
+        if statement_kind and kind != EdgeKind.AMBIGUOUS:
+            kind = statement_kind
+        if targets:
+            for symbol in targets:
+                self._add_call(
+                    caller, node, symbol["id"], symbol["name"], symbol["file"], kind
+                )
+        elif kind == EdgeKind.UNRESOLVED:
+            # 无法确定目标的函数变量：保留调用，边指向以变量名命名的占位节点
+            # （不标记为 go/defer，目标未知比执行方式更需要被注意）
+            placeholder = f"{self.context.package}.{name}"
+            self._add_call(
+                caller,
+                node,
+                self.parser.generate_id("unresolved", placeholder, 0),
+                name,
+                None,
+                EdgeKind.UNRESOLVED,
+            )
+        else:
+            self._add_call(
+                caller,
+                node,
+                self.parser.generate_id("external", name, 0),
+                name,
+                None,
+                statement_kind or EdgeKind.DIRECT,
+            )
+
+    def _add_call(
+        self,
+        caller: Dict[str, Any],
+        node,
+        callee_id: str,
+        callee_name: str,
+        callee_file: Optional[str],
+        kind: str,
+    ):
+        self.calls.append(
+            {
+                "caller_id": caller["id"],
+                "callee_id": callee_id,
+                "caller_name": caller["name"],
+                "callee_name": callee_name,
+                "caller_file": self.file_path,
+                "callee_file": callee_file,
+                "call_site_line": node.start_point[0] + 1,
+                "call_site_column": node.start_point[1],
+                "language": "go",
+                "kind": kind,
+            }
+        )
+
+    # ---- 调用目标解析 ----
+
+    def _is_conversion(self, function) -> bool:
+        """调用位置是类型时（如 MyInt(x)、[]byte(s)、List[int](x)）是类型转换"""
+        if function.type.endswith("_type"):
+            return True
+        if function.type in GENERIC_INSTANTIATIONS:
+            operand = function.child_by_field_name("operand")
+            return operand is not None and self._is_conversion(operand)
+        if function.type == "identifier":
+            name = self.text(function)
+            return name in GO_PREDECLARED_TYPES or (
+                (self.context.package, name) in self.index.types
+            )
+        if function.type == "selector_expression":
+            operand = function.child_by_field_name("operand")
+            field = function.child_by_field_name("field")
+            if operand is not None and field is not None:
+                package = self._imported_package(operand, {})
+                return (package, self.text(field)) in self.index.types
+        return False
+
+    def _resolve(
+        self, function, env: Dict[str, Any]
+    ) -> Tuple[List[Dict[str, Any]], str, str]:
+        """
+        解析调用目标
+
+        返回 (目标函数符号列表, 边类型, 调用名)，
+        无法解析时目标列表为空，调用名用于生成外部函数节点；调用的是无法确定
+        目标的包级函数变量或其他调用返回的函数时目标列表为空、边类型为 unresolved
+        """
+        if function.type == "parenthesized_expression" and function.named_children:
+            return self._resolve(function.named_children[0], env)
+
+        if function.type == "identifier":
+            name = self.text(function)
+            if name in env:
+                # 调用的是局部变量中保存的函数值，只有确定指向哪个函数时才能解析
+                value = env[name]
+                if isinstance(value, FuncValue):
+                    return value.targets, value.kind, name
+                return [], EdgeKind.DIRECT, name
+            symbol = self.index.functions.get((self.context.package, name))
+            if symbol:
+                return [symbol], EdgeKind.DIRECT, name
+            return self._variable_call(self.context.package, name, name)
+
+        if function.type == "selector_expression":
+            operand = function.child_by_field_name("operand")
+            field = function.child_by_field_name("field")
+            if operand is None or field is None:
+                return [], EdgeKind.DIRECT, self.text(function)
+            name = self.text(field)
+
+            # pkg.Func() 形式的跨包调用，无法解析时以 pkg.Func 作为外部函数名
+            package = self._imported_package(operand, env)
+            if package is not None:
+                symbol = self.index.functions.get((package, name))
+                if symbol:
+                    return [symbol], EdgeKind.DIRECT, name
+                return self._variable_call(
+                    package, name, f"{self.text(operand)}.{name}"
+                )
+
+            # T.Method(x)、(*T).Method(x) 形式的方法表达式，接收者是第一个参数
+            ref = self._method_expression_type(operand, env)
+            if ref is not None:
+                symbols, kind = self.index.lookup_method(
+                    ref, name, self.options.resolve_interfaces
+                )
+                return symbols, kind, name
+
+            # x.Method() 形式的方法调用，按 x 的静态类型查找
+            ref = self._infer(operand, env)
+            if ref is not None:
+                symbols, kind = self.index.lookup_method(
+                    ref, name, self.options.resolve_interfaces
+                )
+                if symbols or kind != EdgeKind.DIRECT:
+                    return symbols, kind, name
+                # x.handler() 调用的是函数类型的字段：按项目中给字段赋的值解析，
+                # 无法确定时记录为 unresolved（名称为 类型.字段）
+                owner = self.index.func_field(ref, name)
+                if owner is not None:
+                    targets = self.index.field_targets(owner, name)
+                    if targets:
+                        return targets, EdgeKind.DIRECT, name
+                    return [], EdgeKind.UNRESOLVED, f"{owner[1]}.{name}"
+                return symbols, kind, name
+
+            # 无法推断 x 的类型（range 变量、索引表达式、外部函数的返回值等）：
+            # 可能是项目中任何一个同名的方法，连到所有候选并标记为 ambiguous，
+            # 没有同名方法时作为外部调用
+            if self.options.resolve_ambiguous and not self._external_value(
+                operand, env
+            ):
+                candidates = self.index.methods_named(name, self.context.package)
+                if candidates:
+                    return candidates, EdgeKind.AMBIGUOUS, name
+            return [], EdgeKind.DIRECT, name
+
+        if function.type in GENERIC_INSTANTIATIONS:
+            # Map[int](...)、Pair[int, string](...)：显式实例化的泛型函数，
+            # 所有实例化都解析到同一个泛型函数声明
+            # dispatch["key"]()：调用 map 或切片中保存的函数，
+            # operand 是包级变量时记录为 unresolved
+            operand = function.child_by_field_name("operand")
+            if operand is not None:
+                targets, kind, name = self._resolve(operand, env)
+                if targets or kind == EdgeKind.UNRESOLVED:
+                    return targets, kind, name
+            return [], EdgeKind.DIRECT, self.text(function)
+
+        if function.type == "func_literal":
+            # func() {...}() 立即调用的匿名函数
+            self._invoked_literals.add(function.start_byte)
+            closure = self.symbols_at.get(function.start_byte)
+            return ([closure] if closure else []), EdgeKind.DIRECT, "func"
+
+        if function.type == "call_expression":
+            # middleware(next)(w, r)：调用另一个调用返回的函数，目标无法静态确定，
+            # 记录为 unresolved，名称为内层调用的目标加上 ()（内层调用单独解析）
+            inner = function.child_by_field_name("function")
+            name = self._resolve(inner, env)[2] if inner is not None else "func"
+            return [], EdgeKind.UNRESOLVED, f"{name}()"
+
+        return [], EdgeKind.DIRECT, self.text(function)
+
+    def _variable_call(
+        self, package: str, name: str, call_name: str
+    ) -> Tuple[List[Dict[str, Any]], str, str]:
+        """调用 package 中的 name 不是函数时，按包级函数变量解析"""
+        targets = self.index.variable_targets(package, name)
+        if targets is None:
+            return [], EdgeKind.DIRECT, call_name
+        if targets:
+            return targets, EdgeKind.DIRECT, call_name
+        return [], EdgeKind.UNRESOLVED, call_name
+
+    def _method_expression_type(
+        self, operand, env: Dict[str, Any]
+    ) -> Optional[TypeRef]:
+        """方法表达式 T.Method 中 operand 是项目中的类型时返回该类型"""
+        if operand.type == "parenthesized_expression" and operand.named_children:
+            return self._method_expression_type(operand.named_children[0], env)
+        if operand.type == "unary_expression":
+            # (*T).Method
+            operator = operand.child_by_field_name("operator")
+            inner = operand.child_by_field_name("operand")
+            if operator is None or inner is None or self.text(operator) != "*":
+                return None
+            return self._method_expression_type(inner, env)
+        if operand.type == "pointer_type" and operand.named_children:
+            return self._method_expression_type(operand.named_children[0], env)
+        if operand.type in GENERIC_INSTANTIATIONS:
+            # Stack[int].Push
+            inner = operand.child_by_field_name("operand")
+            if inner is None:
+                return None
+            return self._method_expression_type(inner, env)
+
+        if operand.type == "identifier":
+            name = self.text(operand)
+            ref = (self.context.package, name)
+        elif operand.type == "selector_expression":
+            # pkg.T.Method
+            package_node = operand.child_by_field_name("operand")
+            field = operand.child_by_field_name("field")
+            if package_node is None or field is None:
+                return None
+            package = self._imported_package(package_node, env)
+            if package is None:
+                return None
+            name = self.text(field)
+            ref = (package, name)
+        else:
+            return None
+
+        # 局部变量和类型参数遮蔽同名的类型
+        if name in env or name in self._type_params or ref not in self.index.types:
+            return None
+        return ref
+
+    def _imported_package(self, operand, env: Dict[str, Any]) -> Optional[str]:
+        """operand 是导入包的引用名时返回对应的包标识"""
+        if operand.type != "identifier":
+            return None
+        name = self.text(operand)
+        if name in env or name not in self.context.imports:
+            return None
+        return self.index.canonical_package(self.context.imports[name])
+
+    def _external_value(self, node, env: Dict[str, Any]) -> bool:
+        """
+        表达式的值是否来自项目外部：沿着 a.b、f()、a[i] 找到最左边的标识符，
+        它是外部包（不在分析范围内的导入包）或者保存了外部值的变量
+        """
+        while True:
+            if node.type == "selector_expression":
+                operand = node.child_by_field_name("operand")
+                if operand is None:
+                    return False
+                package = self._imported_package(operand, env)
+                if package is not None:
+                    return package not in self.index.packages
+                node = operand
+            elif node.type == "call_expression":
+                node = node.child_by_field_name("function")
+            elif node.type in ("index_expression", "unary_expression"):
+                node = node.child_by_field_name("operand")
+            elif node.type == "parenthesized_expression" and node.named_children:
+                node = node.named_children[0]
+            elif node.type == "identifier":
+                return isinstance(env.get(self.text(node)), ExternalValue)
+            else:
+                return False
+            if node is None:
+                return False
+
+    # ---- 类型推断 ----
+
+    def _infer(self, node, env: Dict[str, Any]) -> Optional[TypeRef]:
+        """推断表达式的静态类型，无法确定时返回 None"""
+        node_type = node.type
+
+        if node_type == "parenthesized_expression" and node.named_children:
+            return self._infer(node.named_children[0], env)
+
+        if node_type == "identifier":
+            value = env.get(self.text(node))
+            return None if isinstance(value, (FuncValue, ExternalValue)) else value
+
+        if node_type == "composite_literal":
+            type_node = node.child_by_field_name("type")
+            return self._type_ref(type_node)
+
+        if node_type == "unary_expression":
+            operator = node.child_by_field_name("operator")
+            operand = node.child_by_field_name("operand")
+            if operator is not None and operand is not None:
+                if self.text(operator) in ("&", "*"):
+                    return self._infer(operand, env)
+            return None
+
+        if node_type == "type_assertion_expression":
+            return self._type_ref(node.child_by_field_name("type"))
+
+        if node_type == "call_expression":
+            results = self._call_result_types(node, env)
+            return results[0] if results else None
+
+        if node_type == "selector_expression":
+            operand = node.child_by_field_name("operand")
+            field = node.child_by_field_name("field")
+            if operand is None or field is None:
+                return None
+            ref = self._infer(operand, env)
+            if ref is not None:
+                return self.index.field_type(ref, self.text(field))
+            return None
+
+        return None
+
+    def _type_ref(self, type_node) -> Optional[TypeRef]:
+        if type_node is None:
+            return None
+        return self.index.canonical(
+            self.context.type_ref(self.text(type_node), self._type_params)
+        )
+
+    def _call_result_types(self, node, env: Dict[str, Any]) -> List[Optional[TypeRef]]:
+        """调用表达式的返回值类型"""
+        function = node.child_by_field_name("function")
+        if function is None:
+            return []
+
+        if function.type == "identifier":
+            name = self.text(function)
+            if name == "new" and name not in env:
+                arguments = node.child_by_field_name("arguments")
+                if arguments is not None and arguments.named_children:
+                    return [self._type_ref(arguments.named_children[0])]
+                return []
+
+        # 类型转换 T(x) 的结果类型就是 T
+        if self._is_conversion(function):
+            return [self._type_ref(function)]
+
+        targets, kind, _ = self._resolve(function, env)
+        # 目标不确定时不猜测返回值的类型
+        if targets and kind != EdgeKind.AMBIGUOUS:
+            return self.index.result_types(targets[0])
+        return []
+
+    # ---- 变量绑定 ----
+
+    def _bind_params(self, parameter_list, env: Dict[str, Any]):
+        if parameter_list is None:
+            return
+        for declaration in parameter_list.named_children:
+            if declaration.type == "parameter_declaration":
+                ref = self._type_ref(declaration.child_by_field_name("type"))
+            elif declaration.type == "variadic_parameter_declaration":
+                # 可变参数在函数体内是切片
+                ref = None
+            else:
+                continue
+            for name_node in declaration.children_by_field_name("name"):
+                env[self.text(name_node)] = ref
+
+    def _bind_var_spec(self, node, env: Dict[str, Any]):
+        names = [self.text(n) for n in node.children_by_field_name("name")]
+        type_node = node.child_by_field_name("type")
+        value = node.child_by_field_name("value")
+        values = value.named_children if value is not None else []
+        if type_node is not None:
+            ref = self._type_ref(type_node)
+            for i, name in enumerate(names):
+                env[name] = ref
+                # var f func() string = user.Greet：函数类型的变量记录具体的函数值
+                if len(values) == len(names):
+                    func_value = self._value(values[i], env)
+                    if isinstance(func_value, FuncValue):
+                        env[name] = func_value
+            return
+
+        self._bind_names(names, values, env)
+
+    def _bind_assignment(self, left, right, env: Dict[str, Any]):
+        if left is None or right is None:
+            return
+        names = [
+            self.text(n) if n.type == "identifier" else None
+            for n in left.named_children
+        ]
+        self._bind_names(names, right.named_children, env)
+
+    def _rebind_func_values(self, node, env: Dict[str, Any]):
+        """f = g 重新赋值：按源码顺序，之后调用 f 时解析到新的函数值"""
+        left = node.child_by_field_name("left")
+        right = node.child_by_field_name("right")
+        if left is None or right is None:
+            return
+        targets = left.named_children
+        values = right.named_children
+        # 先计算所有右侧的值再赋值（a, b = b, a）
+        if len(values) == len(targets):
+            new_values = [self._value(value, env) for value in values]
+        else:
+            new_values = [None] * len(targets)
+        for target, value in zip(targets, new_values):
+            name = self.text(target) if target.type == "identifier" else None
+            # 只更新记录了函数值的变量，其他变量保持声明时的静态类型
+            if name and isinstance(env.get(name), FuncValue):
+                env[name] = value if isinstance(value, FuncValue) else None
+
+    def _value(self, node, env: Dict[str, Any]):
+        """变量绑定的值：已知的函数值或者推断出的类型"""
+        if node.type == "func_literal":
+            closure = self.symbols_at.get(node.start_byte)
+            return FuncValue([closure]) if closure else None
+        if node.type == "identifier" and isinstance(
+            env.get(self.text(node)), FuncValue
+        ):
+            return env[self.text(node)]
+        if node.type in ("identifier", "selector_expression") + GENERIC_INSTANTIATIONS:
+            # f := helper、f := user.Greet（方法值）、f := User.GetAge（方法表达式）
+            # 等没有被立即调用的函数引用，之后调用 f 时解析到这些函数
+            if not self._is_conversion(node):
+                targets, kind, _ = self._resolve(node, env)
+                if targets:
+                    return FuncValue(targets, kind)
+        ref = self._infer(node, env)
+        if ref is None and self._external_value(node, env):
+            return EXTERNAL_VALUE
+        return ref
+
+    def _bind_names(self, names: List[Optional[str]], values: List, env: Dict):
+        if len(values) == len(names):
+            refs = [self._value(value, env) for value in values]
+        elif len(values) == 1 and values[0].type == "call_expression":
+            # a, err := f() 形式，按返回值位置绑定
+            refs = self._call_result_types(values[0], env)
+            if not refs and self._external_value(values[0], env):
+                refs = [EXTERNAL_VALUE] * len(names)
+        else:
+            refs = []
+
+        for i, name in enumerate(names):
+            if name and name != "_":
+                env[name] = refs[i] if i < len(refs) else None
@@ -0,0 +1,1204 @@
+"""
+调用图数据模型
+从数据库加载函数节点和调用边，供导出和图算法使用
+"""
+
+import json
+import re
+from collections import defaultdict, deque
+from dataclasses import asdict, dataclass, field, fields
+from typing import IO, Any, Callable, Dict, List, Optional, Set, Tuple
+
+
+class EdgeKind:
+    """调用边的类型（以字符串形式存储在数据库和导出结果中）"""
+
+    # 普通的静态调用
+    DIRECT = "direct"
+    # 通过接口变量调用，边指向满足该接口的某个具体实现
+    INTERFACE = "interface"
+    # 在 go 语句中启动的调用（新的 goroutine）
+    GO = "go"
+    # 由 defer 语句延迟到函数返回时执行的调用
+    DEFER = "defer"
+    # 调用的是项目中的函数变量，但无法静态确定指向哪个函数
+    # （保存在 map 中、运行时重新赋值等），边指向以变量名命名的占位节点
+    UNRESOLVED = "unresolved"
+    # 接收者的类型无法确定时按方法名找到的候选之一（x.Greet() 中 x 的类型未知，
+    # 项目中有多个类型声明了 Greet），同一处调用有到每个候选的边
+    AMBIGUOUS = "ambiguous"
+    # 不是调用：具体类型（起点）满足接口（终点），两端都是类型节点
+    IMPLEMENTS = "implements"
+
+
+class ExternalMode:
+    """构建调用图时如何处理指向项目外部函数（标准库、第三方库、无法解析）的调用"""
+
+    # 丢弃外部调用，只保留项目内部的边
+    DROP = "drop"
+    # 所有外部调用合并到一个 <external> 节点
+    GROUP = "group"
+    # 每个外部函数保留为单独的节点
+    KEEP = "keep"
+
+
+class TraversalOrder:
+    """CallGraph.walk() 的遍历顺序"""
+
+    # 深度优先，先访问函数本身，再依次进入它调用的每个函数
+    DFS = "dfs"
+    # 广度优先，按到起点的调用层数逐层访问
+    BFS = "bfs"
+
+
+# GROUP 模式下外部调用汇聚的节点
+EXTERNAL_NODE_ID = "<external>"
+
+# go test 运行的测试函数：Test、Benchmark、Fuzz、Example 后面不能紧跟小写字母
+GO_TEST_FUNCTION_RE = re.compile(r"^(Test|Benchmark|Fuzz|Example)($|[^a-z])")
+
+# 限定名中包名之后的部分：可选的接收者 (*T)、(T) 或 T，加上函数名
+_FUNCTION_PART_RE = re.compile(r"(?:\((\*)?([^()*]+)\)\.|([^.()*]+)\.)?([^.()*\[\]]+)")
+
+
+@dataclass
+class Node:
+    """函数节点"""
+
+    id: str
+    name: str
+    file: Optional[str] = None
+    # 声明所在的行号和列号（都从 1 开始，便于编辑器跳转）
+    line: Optional[int] = None
+    column: Optional[int] = None
+    language: Optional[str] = None
+    # 所属包（Go 为导入路径或包名），其他语言为空
+    package: Optional[str] = None
+    # 方法的接收者类型名（Go），普通函数为空
+    receiver: Optional[str] = None
+    # 是否为项目外部的函数（没有声明位置）
+    external: bool = False
+    # 是否可以被包外部调用（Go 中首字母大写的标识符）
+    exported: bool = False
+    # 节点类型：function，或者 implements 边两端的类型节点
+    # （与数据库中的符号类型相同：struct、interface、type）
+    kind: str = "function"
+    # 声明的第一行源码（如 func (u *User) Greet() string {），外部函数为空
+    signature: Optional[str] = None
+
+    @property
+    def qualified_name(self) -> str:
+        """限定名：包名.函数名，例如 example.com/app/model.User.Greet"""
+        if self.package:
+            return f"{self.package}.{self.name}"
+        return self.name
+
+    def sort_key(self) -> Tuple[bool, str, str, int, int, str]:
+        # 外部函数排在项目函数之后；限定名相同（重复声明、不同语言的同名函数）
+        # 时依次按文件、位置和节点 ID 区分，保证任何两个节点的顺序都是确定的
+        return (
+            self.external,
+            self.qualified_name,
+            self.file or "",
+            self.line or 0,
+            self.column or 0,
+            self.id,
+        )
+
+
+@dataclass(frozen=True)
+class CallSite:
+    """调用所在的位置（行号和列号都从 1 开始）"""
+
+    file: Optional[str]
+    line: Optional[int]
+    column: Optional[int] = None
+
+    def sort_key(self) -> Tuple[str, int, int]:
+        return (self.file or "", self.line or 0, self.column or 0)
+
+    def __str__(self) -> str:
+        text = f"{self.file or '?'}:{self.line if self.line is not None else '?'}"
+        if self.column is not None:
+            text += f":{self.column}"
+        return text
+
+
+@dataclass
+class Edge:
+    """调用边，同一对函数之间同一类型的多处调用合并为一条边"""
+
+    caller: str
+    callee: str
+    kind: str = EdgeKind.DIRECT
+    # 边的权重：包级别调用图中为两个包之间不同的函数调用对的个数，其他情况为 1
+    # （elide() 合并的边为合并前各边的权重之和）
+    weight: int = 1
+    # 合并到这条边的所有调用位置（不重复，按文件、行号、列号排序）
+    call_sites: List[CallSite] = field(default_factory=list)
+
+    @property
+    def count(self) -> int:
+        """调用次数：不同调用位置的个数（没有位置信息的边为 0）"""
+        return len(self.call_sites)
+
+    def add_call_site(self, site: CallSite):
+        if site not in self.call_sites:
+            self.call_sites.append(site)
+
+
+@dataclass
+class GraphStats:
+    """调用图的整体统计，见 CallGraph.stats()"""
+
+    nodes: int
+    edges: int
+    # 弱连通分量的个数（不考虑调用方向时互相连通的函数为一组）
+    components: int
+    # 包含递归调用的强连通分量的个数（与 cycles() 找到的环的个数相同）
+    cyclic_components: int
+    # 扇入/扇出的最大值和平均值，计数规则见 NodeMetrics
+    max_fan_in: int
+    avg_fan_in: float
+    max_fan_out: int
+    avg_fan_out: float
+    # 最长调用链的调用层数（每个递归调用环缩为一个节点计算）
+    longest_chain: int
+    # 从入口出发无法到达的函数个数，没有指定入口时为 None
+    unreachable: Optional[int] = None
+
+    def __str__(self) -> str:
+        lines = [
+            f"节点数: {self.nodes}",
+            f"调用边数: {self.edges}",
+            f"连通分量: {self.components}",
+            f"递归调用环: {self.cyclic_components}",
+            f"扇入: 最大 {self.max_fan_in}，平均 {self.avg_fan_in:.2f}",
+            f"扇出: 最大 {self.max_fan_out}，平均 {self.avg_fan_out:.2f}",
+            f"最长调用链: {self.longest_chain} 层",
+        ]
+        if self.unreachable is not None:
+            lines.append(f"无法到达的函数: {self.unreachable}")
+        return "\n".join(lines)
+
+
+@dataclass(frozen=True)
+class QualifiedName:
+    """parse_qualified_name() 的结果"""
+
+    package: str
+    # 方法的接收者类型名（不含 * 和类型参数），普通函数为空字符串
+    receiver: str
+    name: str
+    # 接收者是否写成了指针形式 (*T)
+    pointer: bool = False
+
+    def __str__(self) -> str:
+        """调用图中使用的限定名（与 Node.qualified_name 相同，接收者不带 *）"""
+        if self.receiver:
+            return f"{self.package}.{self.receiver}.{self.name}"
+        return f"{self.package}.{self.name}"
+
+
+def parse_qualified_name(text: str) -> QualifiedName:
+    """
+    解析 Go 函数的限定名，例如 main.add、main.User.Greet、main.(*User).Greet
+    和 example.com/app/model.User.Greet
+
+    包名到最后一个 "/" 之后的第一个 "." 为止（与 Go 工具链打印的函数名相同，
+    最后一段包含 "." 的导入路径如 gopkg.in/yaml.v3 因此无法直接表示）；
+    接收者可以写成 (*T)、(T) 或 T，泛型接收者的类型参数（Stack[T]）会被去掉。
+    格式不正确时抛出 ValueError。
+    """
+    head = re.split(r"[(\[]", text, maxsplit=1)[0]
+    dot = text.find(".", head.rfind("/") + 1)
+    match = _FUNCTION_PART_RE.fullmatch(text[dot + 1 :]) if dot > 0 else None
+    if match is None:
+        raise ValueError(f"无效的限定名: {text!r}")
+    pointer, paren_receiver, receiver, name = match.groups()
+    receiver = re.sub(r"\[.*\]$", "", paren_receiver or receiver or "").strip()
+    if (paren_receiver or receiver) and not receiver:
+        raise ValueError(f"无效的限定名: {text!r}")
+    return QualifiedName(text[:dot], receiver, name, pointer is not None)
+
+
+@dataclass
+class AmbiguousCall:
+    """一处目标不确定的方法调用，见 CallGraph.ambiguous_calls()"""
+
+    site: CallSite
+    caller: Node
+    # 按方法名找到的所有候选（按节点顺序），实际调用的是其中之一
+    candidates: List[Node]
+
+
+class UnresolvedCallsError(ValueError):
+    """严格模式下存在无法解析的调用，calls 为这些调用的位置（已排序）"""
+
+    def __init__(self, message: str, calls: List[CallSite]):
+        super().__init__(message)
+        self.calls = calls
+
+
+@dataclass
+class NodeMetrics:
+    """
+    函数的扇入/扇出
+
+    按不同的函数计数而不是按调用处计数：a 中三处调用 b 只算 b 的一个调用者；
+    不同类型的边（direct、go、defer 等）指向同一个函数也只算一次。
+    """
+
+    node: Node
+    # 调用该函数的不同函数个数（入度）
+    fan_in: int = 0
+    # 该函数调用的不同函数个数（出度）
+    fan_out: int = 0
+
+
+class CallGraph:
+    """内存中的调用图"""
+
+    def __init__(self):
+        self.nodes: Dict[str, Node] = {}
+        self.edges: Dict[Tuple[str, str, str], Edge] = {}
+        # 邻接索引：节点 ID -> 出边/入边另一端的节点 ID
+        self._out: Dict[str, Set[str]] = defaultdict(set)
+        self._in: Dict[str, Set[str]] = defaultdict(set)
+        # 名称索引：短名称/限定名 -> 节点 ID 列表
+        self._by_name: Dict[str, List[str]] = defaultdict(list)
+        self._by_qualified_name: Dict[str, List[str]] = defaultdict(list)
+
+    def add_node(self, node: Node) -> Node:
+        """添加节点，已存在时保留原节点"""
+        if node.id in self.nodes:
+            return self.nodes[node.id]
+        self.nodes[node.id] = node
+        self._by_name[node.name].append(node.id)
+        self._by_qualified_name[node.qualified_name].append(node.id)
+        return node
+
+    def add_edge(
+        self,
+        caller: str,
+        callee: str,
+        kind: str = EdgeKind.DIRECT,
+        weight: int = 1,
+    ) -> Optional[Edge]:
+        """添加调用边，两端节点必须已经存在；边已存在时保留原来的边"""
+        if caller not in self.nodes or callee not in self.nodes:
+            return None
+        key = (caller, callee, kind)
+        if key not in self.edges:
+            self.edges[key] = Edge(caller, callee, kind, weight)
+            self._out[caller].add(callee)
+            self._in[callee].add(caller)
+        return self.edges[key]
+
+    def find(self, name: str) -> List[Node]:
+        """
+        按名称查找函数节点
+
+        优先按限定名（如 example.com/app/model.User.Greet）精确匹配；
+        没有匹配时按短名称（如 User.Greet）匹配，短名称在多个包中
+        重名时返回所有同名函数。
+        """
+        ids = self._by_qualified_name.get(name) or self._by_name.get(name, [])
+        return sorted((self.nodes[i] for i in ids), key=Node.sort_key)
+
+    def lookup(self, qualified_name: str) -> Optional[Node]:
+        """
+        按限定名查找一个函数，找不到时返回 None
+
+        接受 parse_qualified_name() 支持的所有写法，main.(*User).Greet 和
+        main.User.Greet 找到的是同一个方法（Go 中同一类型的方法不会重名，
+        接收者是否为指针不参与匹配）；其他语言的函数和外部函数按限定名精确匹配。
+        与 find() 不同，不会按短名称匹配；限定名相同的多个节点（重复声明）
+        返回按节点顺序排列的第一个。
+        """
+        ids = self._by_qualified_name.get(qualified_name)
+        if not ids:
+            try:
+                ids = self._by_qualified_name.get(
+                    str(parse_qualified_name(qualified_name))
+                )
+            except ValueError:
+                return None
+        if not ids:
+            return None
+        return min((self.nodes[i] for i in ids), key=Node.sort_key)
+
+    def callers(self, name: str) -> List[Node]:
+        """
+        查询调用指定函数的所有函数（去重，结果已排序）
+
+        name 可以是限定名或短名称，匹配规则见 find()；短名称匹配到多个
+        函数时，返回的是所有这些函数的调用者的并集。
+        """
+        return self._neighbors(name, self._in)
+
+    def callees(self, name: str) -> List[Node]:
+        """查询指定函数调用的所有函数，名称匹配规则与 callers() 相同"""
+        return self._neighbors(name, self._out)
+
+    def _neighbors(self, name: str, index: Dict[str, Set[str]]) -> List[Node]:
+        ids = set()
+        for node in self.find(name):
+            ids.update(index.get(node.id, ()))
+        return sorted((self.nodes[i] for i in ids), key=Node.sort_key)
+
+    def callers_of(self, node_id: str) -> List[Node]:
+        """按节点 ID 查询直接调用者（已排序），节点不存在时返回空列表"""
+        return sorted(
+            (self.nodes[i] for i in self._in.get(node_id, ())), key=Node.sort_key
+        )
+
+    def callees_of(self, node_id: str) -> List[Node]:
+        """按节点 ID 查询直接被调用者（已排序），节点不存在时返回空列表"""
+        return sorted(
+            (self.nodes[i] for i in self._out.get(node_id, ())), key=Node.sort_key
+        )
+
+    def sorted_nodes(self) -> List[Node]:
+        """按限定名排序的节点列表（外部函数在后），保证导出结果稳定"""
+        return sorted(self.nodes.values(), key=Node.sort_key)
+
+    def sorted_edges(self) -> List[Edge]:
+        """按调用者、被调用者（节点顺序）和边类型排序的边列表"""
+        return sorted(
+            self.edges.values(),
+            key=lambda e: (
+                self.nodes[e.caller].sort_key(),
+                self.nodes[e.callee].sort_key(),
+                e.kind,
+            ),
+        )
+
+    def stats(self, entries: Optional[List[str]] = None) -> GraphStats:
+        """
+        调用图的整体统计（适合在画图之前先了解一个项目的规模和结构）
+
+        entries 为入口函数名称列表（匹配规则见 find()），指定时统计从这些入口
+        无法到达的函数个数（规则见 unreachable()），找不到入口时抛出 ValueError。
+        """
+        metrics = list(self.metrics().values())
+        fan_in = [m.fan_in for m in metrics]
+        fan_out = [m.fan_out for m in metrics]
+        return GraphStats(
+            nodes=len(self.nodes),
+            edges=len(self.edges),
+            components=self._weak_component_count(),
+            cyclic_components=len(self.cycles()),
+            max_fan_in=max(fan_in, default=0),
+            avg_fan_in=sum(fan_in) / len(fan_in) if fan_in else 0.0,
+            max_fan_out=max(fan_out, default=0),
+            avg_fan_out=sum(fan_out) / len(fan_out) if fan_out else 0.0,
+            longest_chain=self._longest_chain(),
+            unreachable=len(self.unreachable(entries)) if entries else None,
+        )
+
+    def _weak_component_count(self) -> int:
+        seen: Set[str] = set()
+        count = 0
+        for root in self.nodes:
+            if root in seen:
+                continue
+            count += 1
+            seen.add(root)
+            queue = deque([root])
+            while queue:
+                current = queue.popleft()
+                neighbors = self._out.get(current, set()) | self._in.get(current, set())
+                for other in neighbors - seen:
+                    seen.add(other)
+                    queue.append(other)
+        return count
+
+    def _longest_chain(self) -> int:
+        """
+        强连通分量缩点后的最长路径（边数）；Tarjan 算法按逆拓扑序输出分量，
+        处理每个分量时它调用的分量都已经计算过
+        """
+        components = self._strongly_connected_components()
+        component_of = {
+            node_id: i
+            for i, component in enumerate(components)
+            for node_id in component
+        }
+        longest = [0] * len(components)
+        for i, component in enumerate(components):
+            for node_id in component:
+                for succ in self._out.get(node_id, ()):
+                    j = component_of[succ]
+                    if j != i:
+                        longest[i] = max(longest[i], longest[j] + 1)
+        return max(longest, default=0)
+
+    def unresolved_calls(self) -> List[CallSite]:
+        """无法确定目标的调用（unresolved 边）的所有位置，按文件、行号、列号排序"""
+        sites = {
+            site
+            for edge in self.edges.values()
+            if edge.kind == EdgeKind.UNRESOLVED
+            for site in edge.call_sites
+        }
+        return sorted(sites, key=CallSite.sort_key)
+
+    def ambiguous_calls(self) -> List[AmbiguousCall]:
+        """
+        接收者类型无法确定、按方法名匹配到项目中的方法的调用（ambiguous 边），
+        每处调用列出所有候选，按调用位置排序
+        """
+        candidates: Dict[Tuple[CallSite, str], List[Node]] = defaultdict(list)
+        for edge in self.sorted_edges():
+            if edge.kind != EdgeKind.AMBIGUOUS:
+                continue
+            for site in edge.call_sites:
+                candidates[(site, edge.caller)].append(self.nodes[edge.callee])
+        calls = []
+        for site, caller in sorted(
+            candidates, key=lambda key: (key[0].sort_key(), key[1])
+        ):
+            nodes = sorted(candidates[(site, caller)], key=Node.sort_key)
+            calls.append(AmbiguousCall(site, self.nodes[caller], nodes))
+        return calls
+
+    def check_resolved(self):
+        """
+        有无法确定目标的调用时抛出 UnresolvedCallsError，
+        错误信息中每处调用一行: 文件:行:列 调用者 -> 调用名
+        """
+        lines = []
+        for edge in self.edges.values():
+            if edge.kind != EdgeKind.UNRESOLVED:
+                continue
+            caller = self.nodes[edge.caller].qualified_name
+            callee = self.nodes[edge.callee].name
+            for site in edge.call_sites:
+                lines.append((site.sort_key(), f"  {site} {caller} -> {callee}"))
+        if lines:
+            lines.sort()
+            raise UnresolvedCallsError(
+                f"{len(lines)} 处调用无法确定目标:\n"
+                + "\n".join(line for _, line in lines),
+                self.unresolved_calls(),
+            )
+
+    def metrics(self) -> Dict[str, NodeMetrics]:
+        """每个函数的扇入/扇出，{节点 ID: NodeMetrics}，按节点排序"""
+        return {
+            node.id: NodeMetrics(
+                node,
+                fan_in=len(self._in.get(node.id, ())),
+                fan_out=len(self._out.get(node.id, ())),
+            )
+            for node in self.sorted_nodes()
+        }
+
+    def most_called(self, limit: int = 10) -> List[NodeMetrics]:
+        """扇入最大的 limit 个函数（被最多不同函数调用），0 表示全部"""
+        return self._top_metrics(lambda m: m.fan_in, limit)
+
+    def most_calling(self, limit: int = 10) -> List[NodeMetrics]:
+        """扇出最大的 limit 个函数（调用了最多不同函数），0 表示全部"""
+        return self._top_metrics(lambda m: m.fan_out, limit)
+
+    def _top_metrics(self, value, limit: int) -> List[NodeMetrics]:
+        if limit < 0:
+            raise ValueError(f"数量不能为负数: {limit}")
+        # 数值相同时保持节点的排序，结果稳定
+        ranked = sorted(self.metrics().values(), key=lambda m: -value(m))
+        return ranked[:limit] if limit else ranked
+
+    def subgraph(self, node_ids) -> "CallGraph":
+        """由指定节点及它们之间的所有边构成的子图（诱导子图）"""
+        keep = set(node_ids)
+        graph = CallGraph()
+        for node in self.sorted_nodes():
+            if node.id in keep:
+                graph.add_node(node)
+        for edge in self.sorted_edges():
+            if edge.caller in keep and edge.callee in keep:
+                copy = graph.add_edge(edge.caller, edge.callee, edge.kind, edge.weight)
+                copy.call_sites = list(edge.call_sites)
+        return graph
+
+    def collapse_by_package(self, self_edges: bool = False) -> "CallGraph":
+        """
+        包级别的调用图（由实际的调用关系而不是 import 得到的包依赖图）
+
+        每个包一个节点（ID 和名称都是包名），包 A 中任一函数调用了包 B 中的
+        函数时有一条 A -> B 的边，边的 weight 为两个包之间不同的
+        （调用者, 被调用者）函数对的个数，可以用来表示耦合程度；
+        同一对函数之间的多种调用（direct、go、defer 等）只算一次。
+        外部函数合并为一个 <external> 节点，没有包信息的项目函数
+        （Go 以外的语言）不包含在结果中。
+
+        Args:
+            self_edges: 是否保留包内部的调用（包到自身的边）
+        """
+
+        def package_of(node: Node) -> Optional[str]:
+            return EXTERNAL_NODE_ID if node.external else node.package
+
+        graph = CallGraph()
+        weights: Dict[Tuple[str, str], int] = defaultdict(int)
+        for node in self.sorted_nodes():
+            source = package_of(node)
+            # implements 边不是调用关系，不计入包之间的耦合
+            if not source or node.kind != "function":
+                continue
+            if source not in graph.nodes:
+                graph.add_node(
+                    Node(
+                        id=source,
+                        name=source,
+                        language=node.language,
+                        external=node.external,
+                    )
+                )
+            for callee in self._out.get(node.id, ()):
+                target = package_of(self.nodes[callee])
+                if target and (self_edges or target != source):
+                    weights[(source, target)] += 1
+
+        for (source, target), weight in sorted(weights.items()):
+            graph.add_edge(source, target, weight=weight)
+        return graph
+
+    def reachable(self, entry: str, max_depth: int = 0) -> "CallGraph":
+        """
+        从入口函数出发可以到达的子图
+
+        按广度优先搜索计算每个函数到入口的最短调用距离，保留距离不超过
+        max_depth 的函数以及它们之间的所有调用边。max_depth 为 0 表示不限制深度。
+        entry 的匹配规则见 find()，匹配到多个函数时都作为入口（深度 0）。
+        """
+        if max_depth < 0:
+            raise ValueError(f"深度不能为负数: {max_depth}")
+        return self.subgraph(self._bfs(self._find_ids(entry), max_depth))
+
+    def prune(self, entries: List[str]) -> "CallGraph":
+        """
+        只保留从一组自定义入口出发可以到达的函数（例如一个服务的所有 HTTP handler）
+
+        结果是各入口可到达函数的并集构成的诱导子图，保留的边的类型、权重和调用位置
+        不变，原图不会被修改。每个入口的匹配规则见 find()，可以与
+        collapse_by_package() 组合得到某个入口涉及的包之间的调用图。
+        """
+        if not entries:
+            raise ValueError("至少需要指定一个入口函数")
+        starts = []
+        for entry in entries:
+            starts.extend(self._find_ids(entry))
+        return self.subgraph(self._bfs(starts))
+
+    def filter_by_regexp(
+        self, pattern: str, include_neighbors: bool = False
+    ) -> "CallGraph":
+        """
+        只保留限定名匹配正则表达式的函数（re.search，例如 "/repository\\."）
+
+        结果是匹配的函数构成的诱导子图，原图不会被修改。include_neighbors 为 True 时
+        同时保留匹配函数的直接调用者和被调用者（一层上下文），以及这些函数之间的
+        所有边。正则表达式无效时抛出 ValueError。
+        """
+        try:
+            regexp = re.compile(pattern)
+        except re.error as e:
+            raise ValueError(f"无效的正则表达式 {pattern!r}: {e}") from e
+
+        matched = {
+            node.id
+            for node in self.nodes.values()
+            if regexp.search(node.qualified_name)
+        }
+        keep = set(matched)
+        if include_neighbors:
+            for node_id in matched:
+                keep.update(self._out.get(node_id, ()))
+                keep.update(self._in.get(node_id, ()))
+        return self.subgraph(keep)
+
+    def elide(self, names: List[str]) -> "CallGraph":
+        """
+        省略包装函数（must、trace 等），把 a -> must -> b 合并为 a -> b，
+        原图不会被修改
+
+        names 中每个名称按 find() 的规则匹配（同名函数都会被省略），找不到时
+        抛出 ValueError。被省略的函数 w 从图中移除，w 的每个调用者 a 直接连到
+        w 调用的每个函数 b；w 调用的是另一个被省略的函数时继续向下合并，
+        直到遇到没有被省略的函数。合并得到的边：
+        - 类型：a -> w 为 direct 时取 w -> b 的类型，否则取 a -> w 的类型
+          （go trace(...) 合并后仍然是 go 边）
+        - 权重和调用位置：取 a -> w 的权重和 a 中调用 w 的位置
+        与已经存在的同类型的边（或经过其他被省略的函数得到的边）合并时，
+        权重相加，调用位置去重合并（包级别的调用图省略包时权重仍然是耦合程度）。
+        a 与 b 相同时保留为递归调用。
+        """
+        elided: Set[str] = set()
+        for name in names:
+            elided.update(self._find_ids(name))
+
+        out_edges: Dict[str, List[Edge]] = defaultdict(list)
+        for edge in self.sorted_edges():
+            out_edges[edge.caller].append(edge)
+        # 被省略的函数 -> 经过被省略的函数到达的 [(被调用者, 最后一条边的类型)]
+        targets: Dict[str, List[Tuple[str, str]]] = {}
+
+        def targets_of(wrapper: str) -> List[Tuple[str, str]]:
+            if wrapper not in targets:
+                found: List[Tuple[str, str]] = []
+                seen = {wrapper}
+                stack = [wrapper]
+                while stack:
+                    for edge in out_edges[stack.pop()]:
+                        if edge.callee not in elided:
+                            if (edge.callee, edge.kind) not in found:
+                                found.append((edge.callee, edge.kind))
+                        elif edge.callee not in seen:
+                            seen.add(edge.callee)
+                            stack.append(edge.callee)
+                targets[wrapper] = found
+            return targets[wrapper]
+
+        graph = self.subgraph(set(self.nodes) - elided)
+        for edge in self.sorted_edges():
+            if edge.caller in elided or edge.callee not in elided:
+                continue
+            for callee, kind in targets_of(edge.callee):
+                if edge.kind != EdgeKind.DIRECT:
+                    kind = edge.kind
+                key = (edge.caller, callee, kind)
+                if key in graph.edges:
+                    merged = graph.edges[key]
+                    merged.weight += edge.weight
+                else:
+                    merged = graph.add_edge(edge.caller, callee, kind, edge.weight)
+                for site in edge.call_sites:
+                    merged.add_call_site(site)
+        for edge in graph.edges.values():
+            edge.call_sites.sort(key=CallSite.sort_key)
+        return graph
+
+    def with_leaves(self, prefixes: List[str]) -> "CallGraph":
+        """
+        把限定名以 prefixes 中任一前缀开头的函数作为叶子节点（例如 "fmt."、
+        "example.com/app/logging."），原图不会被修改
+
+        叶子节点保留所有来自其他函数的调用边（可以有任意多个调用者），
+        但不再有从它出发的边，因此 reachable() 等遍历不会进入它的内部；
+        只被其他叶子节点调用的叶子函数（日志包的内部实现等）从结果中移除。
+        前缀按字符串匹配，需要精确到包时以 "." 结尾。
+        """
+        if not prefixes:
+            raise ValueError("至少需要指定一个前缀")
+        leaves = {
+            node.id
+            for node in self.nodes.values()
+            if any(node.qualified_name.startswith(prefix) for prefix in prefixes)
+        }
+        edges = [edge for edge in self.sorted_edges() if edge.caller not in leaves]
+        called = {edge.callee for edge in edges}
+
+        graph = CallGraph()
+        for node in self.sorted_nodes():
+            if node.id not in leaves or node.id in called or not self._in.get(node.id):
+                graph.add_node(node)
+        for edge in edges:
+            copy = graph.add_edge(edge.caller, edge.callee, edge.kind, edge.weight)
+            copy.call_sites = list(edge.call_sites)
+        return graph
+
+    def path(self, source: str, target: str) -> Optional[List[Node]]:
+        """
+        两个函数之间最短的调用路径（广度优先搜索）
+
+        返回从 source 到 target 经过的函数（包含两端），没有调用路径时返回 None；
+        source 和 target 是同一个函数时返回只包含该函数的路径。
+        名称匹配规则见 find()，匹配到多个函数时返回其中最短的一条路径，
+        长度相同时按节点顺序选择，结果稳定。
+        """
+        starts = self._find_ids(source)
+        targets = set(self._find_ids(target))
+        parents: Dict[str, Optional[str]] = dict.fromkeys(starts)
+        queue = deque(starts)
+        while queue:
+            current = queue.popleft()
+            if current in targets:
+                path = [current]
+                while parents[path[-1]] is not None:
+                    path.append(parents[path[-1]])
+                return [self.nodes[i] for i in reversed(path)]
+            for succ in self._successors(current):
+                if succ not in parents:
+                    parents[succ] = current
+                    queue.append(succ)
+        return None
+
+    def all_paths(
+        self, source: str, target: str, max_length: int
+    ) -> List[List[Node]]:
+        """
+        两个函数之间所有不超过 max_length 次调用的路径
+
+        路径中不会重复经过同一个函数，到达 target 后不再继续延伸，
+        调用环不会导致死循环。路径数量可能随长度指数增长，max_length
+        同时限制了搜索的深度。结果按路径长度、再按节点顺序排序。
+        名称匹配规则见 find()。
+        """
+        if max_length <= 0:
+            raise ValueError(f"路径长度必须大于 0: {max_length}")
+        targets = set(self._find_ids(target))
+        paths: List[List[str]] = []
+
+        def extend(path: List[str], visited: Set[str]):
+            current = path[-1]
+            if current in targets:
+                paths.append(list(path))
+                return
+            if len(path) > max_length:
+                return
+            for succ in self._successors(current):
+                if succ not in visited:
+                    visited.add(succ)
+                    path.append(succ)
+                    extend(path, visited)
+                    path.pop()
+                    visited.discard(succ)
+
+        for start in self._find_ids(source):
+            extend([start], {start})
+
+        paths.sort(key=lambda p: (len(p), [self.nodes[i].sort_key() for i in p]))
+        return [[self.nodes[i] for i in path] for path in paths]
+
+    def _find_ids(self, name: str) -> List[str]:
+        """find() 的节点 ID 列表，找不到时抛出 ValueError"""
+        nodes = self.find(name)
+        if not nodes:
+            raise ValueError(f"找不到函数: {name}")
+        return [node.id for node in nodes]
+
+    def unreachable(
+        self,
+        entries: List[str],
+        include_exported: bool = False,
+        include_init: bool = False,
+    ) -> List[Node]:
+        """
+        从入口函数出发无法到达的函数（可能是死代码）
+
+        Args:
+            entries: 入口函数名称列表（匹配规则见 find()）
+            include_exported: 是否把所有导出的函数也当作入口，
+                分析库代码时导出函数可能被包外部调用
+            include_init: 是否把 Go 的 init 函数也当作入口（由运行时自动调用）
+
+        分析了测试文件时，其中的测试函数（TestXxx 等，由 go test 调用）
+        总是作为入口。
+        """
+        starts = []
+        for entry in entries:
+            starts.extend(self._find_ids(entry))
+
+        for node in self.nodes.values():
+            if include_exported and node.exported:
+                starts.append(node.id)
+            elif include_init and self._is_go_init(node):
+                starts.append(node.id)
+            elif self._is_go_test(node):
+                starts.append(node.id)
+
+        reached = self._bfs(starts)
+        return [
+            node
+            for node in self.sorted_nodes()
+            if node.id not in reached
+            and not node.external
+            and node.kind == "function"
+        ]
+
+    @staticmethod
+    def _is_go_init(node: Node) -> bool:
+        return node.language == "go" and node.name == "init" and not node.receiver
+
+    @staticmethod
+    def _is_go_test(node: Node) -> bool:
+        """是否为 _test.go 文件中由 go test 调用的测试函数"""
+        return (
+            node.language == "go"
+            and not node.receiver
+            and (node.file or "").endswith("_test.go")
+            and GO_TEST_FUNCTION_RE.match(node.name) is not None
+        )
+
+    def walk(
+        self,
+        start: str,
+        order: str,
+        visit: Callable[[Node, int], Optional[bool]],
+    ):
+        """
+        从 start 出发沿调用边遍历，对每个到达的节点调用 visit(节点, 深度)
+
+        order 为 TraversalOrder.DFS（前序深度优先，深度是遍历路径上的调用层数）
+        或 TraversalOrder.BFS（逐层广度优先，深度是到起点的最短调用层数）。
+        visit 返回 False 时不再进入该节点调用的函数（返回 None 视为继续），
+        其他路径仍然可以到达这些函数。每个节点最多访问一次，调用环不会导致
+        死循环；同一层中按节点顺序访问，结果稳定。start 的匹配规则见 find()，
+        匹配到多个函数时都作为起点（深度 0）。
+        """
+        starts = self._find_ids(start)
+        if order == TraversalOrder.BFS:
+            visited = set(starts)
+            queue = deque((node_id, 0) for node_id in starts)
+            while queue:
+                current, depth = queue.popleft()
+                if visit(self.nodes[current], depth) is False:
+                    continue
+                for succ in self._successors(current):
+                    if succ not in visited:
+                        visited.add(succ)
+                        queue.append((succ, depth + 1))
+        elif order == TraversalOrder.DFS:
+            visited = set()
+            stack = [(node_id, 0) for node_id in reversed(starts)]
+            while stack:
+                current, depth = stack.pop()
+                if current in visited:
+                    continue
+                visited.add(current)
+                if visit(self.nodes[current], depth) is False:
+                    continue
+                # 逆序入栈，先访问排序靠前的被调用者
+                for succ in reversed(self._successors(current)):
+                    if succ not in visited:
+                        stack.append((succ, depth + 1))
+        else:
+            raise ValueError(f"不支持的遍历顺序: {order}")
+
+    def _bfs(self, starts: List[str], max_depth: int = 0) -> Dict[str, int]:
+        """广度优先搜索，返回 {可到达的节点 ID: 到最近起点的调用层数}"""
+        depth = dict.fromkeys(starts, 0)
+        queue = deque(depth)
+        while queue:
+            current = queue.popleft()
+            if max_depth and depth[current] >= max_depth:
+                continue
+            for succ in self._successors(current):
+                # 已访问的节点不会再次入队，调用环不会导致死循环
+                if succ not in depth:
+                    depth[succ] = depth[current] + 1
+                    queue.append(succ)
+        return depth
+
+    def cycles(self) -> List[List[Node]]:
+        """
+        查找所有递归调用环
+
+        使用 Tarjan 算法求强连通分量，返回：
+        - 每个包含多个函数的强连通分量（互相递归）
+        - 每个直接调用自身的函数（自递归），作为只有一个元素的环
+
+        每个环是一个从分量中排序最小的函数出发、经过分量中所有函数、
+        最后能回到起点的调用序列，可以直接打印为 a -> b -> c -> a。
+        强连通分量不是简单环时，序列中可能出现重复的函数。
+        """
+        result = []
+        for component in self._strongly_connected_components():
+            if len(component) > 1:
+                result.append(self._closed_walk(component))
+
+        for node in self.sorted_nodes():
+            if node.id in self._out.get(node.id, ()):
+                result.append([node])
+
+        result.sort(key=lambda cycle: [n.sort_key() for n in cycle])
+        return result
+
+    def _strongly_connected_components(self) -> List[Set[str]]:
+        """Tarjan 算法（迭代实现，避免深调用链触发递归深度限制）"""
+        index: Dict[str, int] = {}
+        lowlink: Dict[str, int] = {}
+        on_stack: Set[str] = set()
+        stack: List[str] = []
+        components = []
+
+        for root in (n.id for n in self.sorted_nodes()):
+            if root in index:
+                continue
+            index[root] = lowlink[root] = len(index)
+            stack.append(root)
+            on_stack.add(root)
+            work = [(root, iter(self._successors(root)))]
+
+            while work:
+                node_id, successors = work[-1]
+                advanced = False
+                for succ in successors:
+                    if succ not in index:
+                        index[succ] = lowlink[succ] = len(index)
+                        stack.append(succ)
+                        on_stack.add(succ)
+                        work.append((succ, iter(self._successors(succ))))
+                        advanced = True
+                        break
+                    if succ in on_stack:
+                        lowlink[node_id] = min(lowlink[node_id], index[succ])
+                if advanced:
+                    continue
+
+                work.pop()
+                if work:
+                    parent = work[-1][0]
+                    lowlink[parent] = min(lowlink[parent], lowlink[node_id])
+                if lowlink[node_id] == index[node_id]:
+                    component = set()
+                    while True:
+                        member = stack.pop()
+                        on_stack.discard(member)
+                        component.add(member)
+                        if member == node_id:
+                            break
+                    components.append(component)
+
+        return components
+
+    def _successors(self, node_id: str) -> List[str]:
+        return sorted(
+            self._out.get(node_id, ()), key=lambda i: self.nodes[i].sort_key()
+        )
+
+    def _closed_walk(self, component: Set[str]) -> List[Node]:
+        """在强连通分量内构造一条经过所有成员并能回到起点的调用序列"""
+        ordered = sorted(component, key=lambda i: self.nodes[i].sort_key())
+        start = ordered[0]
+        walk = [start]
+        remaining = set(component) - {start}
+
+        while remaining:
+            # 从当前位置出发，沿分量内的边走到最近的未访问成员
+            path = self._shortest_path_within(walk[-1], remaining, component)
+            walk.extend(path)
+            remaining.difference_update(path)
+
+        # 回到起点（不重复输出起点本身）
+        if start not in self._out.get(walk[-1], ()):
+            path = self._shortest_path_within(walk[-1], {start}, component)
+            walk.extend(path[:-1])
+
+        return [self.nodes[i] for i in walk]
+
+    def _shortest_path_within(
+        self, source: str, targets: Set[str], component: Set[str]
+    ) -> List[str]:
+        """BFS：返回从 source 到 targets 中任一节点的最短路径（不含 source）"""
+        parents = {source: None}
+        queue = deque([source])
+        while queue:
+            current = queue.popleft()
+            for succ in self._successors(current):
+                if succ not in component or succ in parents:
+                    continue
+                parents[succ] = current
+                if succ in targets:
+                    path = [succ]
+                    while parents[path[-1]] != source:
+                        path.append(parents[path[-1]])
+                    return path[::-1]
+                queue.append(succ)
+        return []
+
+    @classmethod
+    def from_db(
+        cls, db, external: str = ExternalMode.DROP, merge_names: bool = False
+    ) -> "CallGraph":
+        """
+        从数据库构建调用图
+
+        Args:
+            db: CallGraphDB
+            external: 外部调用的处理方式（见 ExternalMode），默认丢弃，
+                只保留两端都是项目中函数的调用边。unresolved 类型的边
+                （无法确定目标的函数变量）总是保留，目标为以调用名命名的外部节点
+            merge_names: 把不同包中的同名函数合并为一个节点（保留第一个声明），
+                默认按限定名区分
+        """
+        if external not in (ExternalMode.DROP, ExternalMode.GROUP, ExternalMode.KEEP):
+            raise ValueError(f"不支持的外部调用处理方式: {external}")
+
+        graph = cls()
+        # 重复声明的节点 ID -> 保留的第一个声明的节点 ID
+        duplicates: Dict[str, str] = {}
+        first_declaration: Dict[Tuple[str, str], str] = {}
+
+        for symbol in db.get_symbols_by_kind("function"):
+            column = symbol.get("start_column")
+            node = Node(
+                id=symbol["id"],
+                name=symbol["name"],
+                file=symbol["file"],
+                line=symbol.get("start_line"),
+                column=column + 1 if column is not None else None,
+                language=symbol.get("language"),
+                package=symbol.get("package"),
+                receiver=symbol.get("receiver"),
+                exported=bool(symbol.get("is_exported")),
+                signature=symbol.get("signature"),
+            )
+
+            # 同一个包中同名的函数（不同构建标签的文件、重复解析等）
+            # 只保留第一个声明的位置；Go 允许一个包中有多个 init 函数
+            if node.package and not cls._is_go_init(node):
+                name = node.name if merge_names else node.qualified_name
+                key = (node.language or "", name)
+                if key in first_declaration:
+                    duplicates[node.id] = first_declaration[key]
+                    continue
+                first_declaration[key] = node.id
+
+            graph.add_node(node)
+
+        for relation in db.get_call_relations():
+            caller_id = duplicates.get(relation["caller_id"], relation["caller_id"])
+            callee_id = duplicates.get(relation["callee_id"], relation["callee_id"])
+            if caller_id not in graph.nodes:
+                continue
+            kind = relation["kind"] or EdgeKind.DIRECT
+            if callee_id not in graph.nodes:
+                if kind == EdgeKind.UNRESOLVED or external == ExternalMode.KEEP:
+                    # 无法确定目标的调用总是保留为单独的节点，不受 external 影响
+                    graph.add_node(
+                        Node(
+                            id=callee_id,
+                            name=relation["callee_name"],
+                            language=relation["language"],
+                            external=True,
+                        )
+                    )
+                elif external == ExternalMode.DROP:
+                    continue
+                else:
+                    callee_id = EXTERNAL_NODE_ID
+                    graph.add_node(
+                        Node(id=callee_id, name=EXTERNAL_NODE_ID, external=True)
+                    )
+            edge = graph.add_edge(caller_id, callee_id, kind)
+            column = relation.get("call_site_column")
+            edge.add_call_site(
+                CallSite(
+                    relation.get("caller_file"),
+                    relation.get("call_site_line"),
+                    column + 1 if column is not None else None,
+                )
+            )
+
+        # 被合并的重复声明可能在其他文件中，调用位置重新排序
+        for edge in graph.edges.values():
+            edge.call_sites.sort(key=CallSite.sort_key)
+        return graph
+
+    def add_implements_edges(self, pairs) -> int:
+        """
+        添加 implements 边：具体类型 -> 它满足的接口
+
+        pairs 为 [(类型符号, 接口符号)]（见 GoIndex.implements_pairs），两端的
+        类型作为 kind 为 struct/interface/type 的节点加入图中。返回添加的边数。
+        """
+        added = 0
+        for symbols in pairs:
+            ids = []
+            for symbol in symbols:
+                column = symbol.get("start_column")
+                node = self.add_node(
+                    Node(
+                        id=symbol["id"],
+                        name=symbol["name"],
+                        file=symbol["file"],
+                        line=symbol.get("start_line"),
+                        column=column + 1 if column is not None else None,
+                        language=symbol.get("language"),
+                        package=symbol.get("package"),
+                        exported=bool(symbol.get("is_exported")),
+                        kind=symbol["kind"],
+                    )
+                )
+                ids.append(node.id)
+            key = (ids[0], ids[1], EdgeKind.IMPLEMENTS)
+            if key not in self.edges:
+                self.add_edge(ids[0], ids[1], EdgeKind.IMPLEMENTS)
+                added += 1
+        return added
+
+    def to_dict(self) -> Dict[str, Any]:
+        """
+        转换为可 JSON 序列化的字典
+
+        {"nodes": [{id, package, name, receiver, file, line, column, language,
+                    external, exported, kind, signature}],
+         "edges": [{from, to, kind, weight, count,
+                    call_sites: [{file, line, column}]}]}
+        """
+        return {
+            "nodes": [asdict(node) for node in self.sorted_nodes()],
+            "edges": [
+                {
+                    "from": edge.caller,
+                    "to": edge.callee,
+                    "kind": edge.kind,
+                    "weight": edge.weight,
+                    "count": edge.count,
+                    "call_sites": [asdict(site) for site in edge.call_sites],
+                }
+                for edge in self.sorted_edges()
+            ],
+        }
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "CallGraph":
+        """从 to_dict() 的结果重建调用图，数据不完整时抛出 ValueError"""
+        node_fields = {f.name for f in fields(Node)}
+        graph = cls()
+        try:
+            for item in data["nodes"]:
+                graph.add_node(
+                    Node(**{k: v for k, v in item.items() if k in node_fields})
+                )
+            for item in data["edges"]:
+                edge = graph.add_edge(
+                    item["from"],
+                    item["to"],
+                    item.get("kind", EdgeKind.DIRECT),
+                    item.get("weight", 1),
+                )
+                if edge is None:
+                    raise ValueError(
+                        f"调用边引用了不存在的节点: {item['from']} -> {item['to']}"
+                    )
+                # count 由 call_sites 计算得到，不单独读取
+                for site in item.get("call_sites", []):
+                    edge.add_call_site(CallSite(**site))
+                # 手工编辑或其他工具生成的文件中调用位置不一定有序
+                edge.call_sites.sort(key=CallSite.sort_key)
+        except (KeyError, TypeError) as e:
+            raise ValueError(f"无效的调用图数据: {e}") from e
+        return graph
+
+    def to_json(self, fp: Optional[IO[str]] = None) -> str:
+        """序列化为 JSON；指定 fp 时同时写入该文件对象"""
+        text = json.dumps(self.to_dict(), ensure_ascii=False, indent=2)
+        if fp is not None:
+            fp.write(text)
+        return text
+
+    @classmethod
+    def load_json(cls, fp: IO[str]) -> "CallGraph":
+        """从 to_json() 写出的文件加载调用图，无需重新解析源码"""
+        try:
+            data = json.load(fp)
+        except json.JSONDecodeError as e:
+            raise ValueError(f"无效的 JSON: {e}") from e
+        if not isinstance(data, dict):
+            raise ValueError("无效的调用图数据: 顶层必须是对象")
+        return cls.from_dict(data)
@@ -4,46 +4,230 @@
 
 import argparse
 import json
+import os
 import sys
+import time
 
 # 支持相对导入和直接运行
 try:
-    from .analyzer import CallGraphAnalyzer
+    from .analyzer import CallGraphAnalyzer, prepare_export_graph
     from .analyzer_optimized import CallGraphAnalyzerOptimized
+    from .cancel import AnalysisCancelled, CancelToken
     from .database import CallGraphDB
+    from .diff import diff
+    from .exporters import EXPORTERS, LABEL_FORMATTERS, to_dot
+    from .graph import CallGraph, UnresolvedCallsError
+    from .options import AnalysisOptions, ExportOptions
+    from .server import serve
+    from .watch import watch
 except ImportError:
-    from analyzer import CallGraphAnalyzer
+    from analyzer import CallGraphAnalyzer, prepare_export_graph
     from analyzer_optimized import CallGraphAnalyzerOptimized
+    from cancel import AnalysisCancelled, CancelToken
     from database import CallGraphDB
+    from diff import diff
+    from exporters import EXPORTERS, LABEL_FORMATTERS, to_dot
+    from graph import CallGraph, UnresolvedCallsError
+    from options import AnalysisOptions, ExportOptions
+    from server import serve
+    from watch import watch
+
+
+def analysis_options(args) -> AnalysisOptions:
+    """analyze / watch 命令共用的分析选项"""
+    return AnalysisOptions(
+        resolve_interfaces=not args.no_interfaces,
+        resolve_ambiguous=not args.no_ambiguous,
+        include_tests=args.tests,
+        goos=args.goos,
+        goarch=args.goarch,
+        build_tags=[tag for tag in (args.tags or "").split(",") if tag],
+    )
+
+
+def add_build_arguments(parser):
+    """Go 构建环境相关的参数"""
+    parser.add_argument(
+        "--goos", help="Go 的目标操作系统，决定哪些文件参与分析 (默认: 当前系统)"
+    )
+    parser.add_argument("--goarch", help="Go 的目标架构 (默认: 当前架构)")
+    parser.add_argument(
+        "--tags", help="额外的 Go 构建标签（逗号分隔），如 integration,cgo"
+    )
+
+
+def export_options(args) -> ExportOptions:
+    """export / watch 命令共用的导出格式选项"""
+    return ExportOptions(
+        cluster=not args.no_cluster,
+        call_counts=args.counts,
+        label=LABEL_FORMATTERS.get(args.label),
+        color_by_fan_in=args.color_by_fan_in,
+        group_methods=args.group_methods,
+    )
+
+
+def check_export_arguments(args):
+    """检查 export / watch 命令中需要一起使用的导出参数，不满足时报错退出"""
+    if args.depth and not args.entry:
+        print("错误: --depth 需要与 --entry 一起使用")
+        sys.exit(1)
+    if args.self_edges and not args.by_package:
+        print("错误: --self-edges 需要与 --by-package 一起使用")
+        sys.exit(1)
+    if args.neighbors and args.filter is None:
+        print("错误: --neighbors 需要与 --filter 一起使用")
+        sys.exit(1)
+
+
+def add_export_arguments(parser):
+    """export / watch 命令共用的调用图裁剪和导出格式参数"""
+    parser.add_argument(
+        "--entry", help="只导出从该函数出发可以到达的函数（函数名或限定名）"
+    )
+    parser.add_argument(
+        "--depth",
+        type=int,
+        default=0,
+        help="与 --entry 一起使用，最多导出距离入口几层调用 (默认: 0，不限制)",
+    )
+    parser.add_argument(
+        "--no-cluster",
+        action="store_true",
+        help="DOT 格式下不按包分组（默认每个包一个 cluster 方框）",
+    )
+    parser.add_argument(
+        "--counts",
+        action="store_true",
+        help="DOT 格式下在调用边上标注调用次数（不同调用位置的个数）",
+    )
+    parser.add_argument(
+        "--by-package",
+        action="store_true",
+        help="导出包级别的调用图（每个包一个节点，边上标注函数调用对的个数）",
+    )
+    parser.add_argument(
+        "--self-edges",
+        action="store_true",
+        help="与 --by-package 一起使用，保留包内部的调用",
+    )
+    parser.add_argument(
+        "--merge-names",
+        action="store_true",
+        help="把不同包中的同名函数合并为一个节点（默认按包区分）",
+    )
+    parser.add_argument(
+        "--implements",
+        action="store_true",
+        help="添加 Go 类型节点和 类型 -> 接口 的 implements 边（点线、空心箭头）",
+    )
+    parser.add_argument(
+        "--filter",
+        metavar="REGEXP",
+        help="只导出限定名匹配该正则表达式的函数（如 'repository\\.'）",
+    )
+    parser.add_argument(
+        "--neighbors",
+        action="store_true",
+        help="与 --filter 一起使用，同时保留匹配函数的直接调用者和被调用者",
+    )
+    parser.add_argument(
+        "--leaf",
+        action="append",
+        metavar="PREFIX",
+        help="限定名以该前缀开头的函数作为叶子节点，保留对它的调用但不导出它内部的"
+        "调用（如 'example.com/app/logging.'，可以指定多次）",
+    )
+    parser.add_argument(
+        "--transparent",
+        action="append",
+        metavar="FUNCTION",
+        help="省略该包装函数（如 must、trace），调用者直接连到它调用的函数，"
+        "可以指定多次",
+    )
+    parser.add_argument(
+        "--label",
+        choices=sorted(LABEL_FORMATTERS),
+        help="DOT/Mermaid/SVG 的节点标签: short 只显示函数名, qualified 限定名, "
+        "signature 限定名加参数和返回值 (默认: 函数名，方法为 Type.Method)",
+    )
+    parser.add_argument(
+        "--color-by-fan-in",
+        action="store_true",
+        help="DOT 格式下按扇入给函数着色（黄色到深红，被调用越多颜色越深；"
+        "没有调用者的函数为蓝色）",
+    )
+    parser.add_argument(
+        "--group-methods",
+        action="store_true",
+        help="DOT 格式下把同一类型的方法合并为一个记录节点（类似 UML 类图），"
+        "调用边连接到具体的方法",
+    )
 
 
 def cmd_analyze(args):
     """分析项目命令"""
+    options = analysis_options(args)
+    options.strict_resolution = args.strict
+    if args.packages and (args.exclude or args.no_recursive):
+        print(
+            "错误: --packages 按 go list 的结果选择文件，"
+            "不能与 --exclude/--no-recursive 一起使用"
+        )
+        sys.exit(1)
+    try:
+        cancel = CancelToken(args.timeout)
+    except ValueError as e:
+        print(f"错误: {e}")
+        sys.exit(1)
+
     # 根据参数选择分析器
     if hasattr(args, "fast") and args.fast:
         workers = args.workers if hasattr(args, "workers") else None
-        analyzer = CallGraphAnalyzerOptimized(args.database, num_workers=workers)
+        analyzer = CallGraphAnalyzerOptimized(
+            args.database, num_workers=workers, options=options
+        )
         print(f"使用性能优化模式（多进程并行处理）")
     else:
-        analyzer = CallGraphAnalyzer(args.database)
+        analyzer = CallGraphAnalyzer(args.database, options=options)
 
     try:
         if args.clear:
             print("清空现有数据...")
             analyzer.db.clear_all()
 
-        if hasattr(args, "fast") and args.fast:
+        if args.packages:
+            try:
+                if args.fast:
+                    stats = analyzer.analyze_packages(
+                        args.packages,
+                        args.project_path,
+                        args.batch_size,
+                        cancel=cancel,
+                    )
+                else:
+                    stats = analyzer.analyze_packages(
+                        args.packages, args.project_path, cancel
+                    )
+            except (ValueError, RuntimeError) as e:
+                print(f"错误: {e}")
+                sys.exit(1)
+        elif hasattr(args, "fast") and args.fast:
             batch_size = args.batch_size if hasattr(args, "batch_size") else 100
             stats = analyzer.analyze_project(
                 args.project_path,
                 exclude_dirs=args.exclude.split(",") if args.exclude else None,
                 batch_size=batch_size,
                 show_progress=True,
+                recursive=not args.no_recursive,
+                cancel=cancel,
             )
         else:
             stats = analyzer.analyze_project(
                 args.project_path,
                 exclude_dirs=args.exclude.split(",") if args.exclude else None,
+                recursive=not args.no_recursive,
+                cancel=cancel,
             )
 
         if not (hasattr(args, "fast") and args.fast):
@@ -53,6 +237,14 @@ def cmd_analyze(args):
             print("=" * 50)
             print(json.dumps(stats, indent=2, ensure_ascii=False))
 
+    except UnresolvedCallsError as e:
+        # 严格模式：结果已经保存，列出无法确定目标的调用后以状态码 1 退出
+        print(f"错误: {e}")
+        sys.exit(1)
+    except AnalysisCancelled as e:
+        # 本次分析的结果已经回滚，数据库保持分析之前的状态（--clear 已清空的除外）
+        print(f"\n错误: {e}，没有保存分析结果")
+        sys.exit(1)
     finally:
         analyzer.close()
 
@@ -164,7 +356,7 @@ def cmd_query(args):
         elif args.chain:
             # 查询调用链
             print(f"\n'{args.function}' 的调用链 (深度={args.depth}):\n")
-            chains = db.get_call_chain(args.function, args.depth)
+            chains = db.get_call_chain(args.function, args.depth, args.merge_names)
 
             if not chains:
                 print(f"没有找到 '{args.function}' 的调用链")
@@ -213,7 +405,9 @@ def cmd_query(args):
         elif args.fullpath:
             # 查询完整调用路径
             print(f"\n查询 '{args.function}' 的完整调用路径 (最大深度={args.depth}):\n")
-            result = db.get_full_call_paths(args.function, args.depth)
+            result = db.get_full_call_paths(
+                args.function, args.depth, merge_names=args.merge_names
+            )
 
             if result["full_count"] == 0:
                 print(f"没有找到包含 '{args.function}' 的调用路径")
@@ -231,7 +425,7 @@ def cmd_query(args):
                     path_parts = []
                     for func_info in detailed_path:
                         # 高亮目标函数
-                        if func_info["name"] == args.function:
+                        if func_info["target"]:
                             path_parts.append(f"[{func_info['display']}]")
                         else:
                             path_parts.append(func_info["display"])
@@ -316,10 +510,163 @@ def cmd_stats(args):
         for kind, count in sorted(stats["by_kind"].items()):
             print(f"  {kind:15s}: {count:6d} 个")
 
+        try:
+            graph_stats = CallGraph.from_db(db).stats(args.entry)
+        except ValueError as e:
+            print(f"错误: {e}")
+            sys.exit(1)
+        print("\n调用图结构:")
+        for line in str(graph_stats).splitlines():
+            print(f"  {line}")
+
     finally:
         db.close()
 
 
+def cmd_cycles(args):
+    """递归环检测命令"""
+    analyzer = CallGraphAnalyzer(args.database)
+
+    try:
+        cycles = analyzer.load_graph().cycles()
+
+        if not cycles:
+            print("没有发现递归调用")
+            return
+
+        print(f"\n发现 {len(cycles)} 个递归调用环:\n")
+        for i, cycle in enumerate(cycles, 1):
+            names = [node.name for node in cycle] + [cycle[0].name]
+            print(f"{i}. {' -> '.join(names)}")
+            if args.verbose:
+                for node in cycle:
+                    print(f"   {node.qualified_name} - {node.file}:{node.line}")
+                print()
+
+    finally:
+        analyzer.close()
+
+
+def cmd_unreachable(args):
+    """死代码检测命令"""
+    analyzer = CallGraphAnalyzer(args.database)
+
+    try:
+        graph = analyzer.load_graph()
+        try:
+            nodes = graph.unreachable(args.entry, args.exported, args.init)
+        except ValueError as e:
+            print(f"错误: {e}")
+            sys.exit(1)
+
+        entries = ", ".join(args.entry)
+        if not nodes:
+            print(f"从 {entries} 出发可以到达所有函数")
+            return
+
+        print(f"\n从 {entries} 出发无法到达 {len(nodes)} 个函数:\n")
+        for i, node in enumerate(nodes, 1):
+            print(f"{i}. {node.qualified_name} - {node.file}:{node.line}")
+
+    finally:
+        analyzer.close()
+
+
+def cmd_ambiguous(args):
+    """列出目标不确定的方法调用"""
+    analyzer = CallGraphAnalyzer(args.database)
+
+    try:
+        calls = analyzer.load_graph().ambiguous_calls()
+        if not calls:
+            print("没有目标不确定的方法调用")
+            return
+
+        print(f"\n{len(calls)} 处方法调用无法确定接收者的类型:\n")
+        for i, call in enumerate(calls, 1):
+            print(f"{i}. {call.site} ({call.caller.qualified_name})")
+            for node in call.candidates:
+                print(f"     -> {node.qualified_name} - {node.file}:{node.line}")
+
+    finally:
+        analyzer.close()
+
+
+def cmd_metrics(args):
+    """扇入/扇出统计命令"""
+    analyzer = CallGraphAnalyzer(args.database)
+
+    try:
+        graph = analyzer.load_graph()
+        try:
+            most_called = graph.most_called(args.top)
+            most_calling = graph.most_calling(args.top)
+        except ValueError as e:
+            print(f"错误: {e}")
+            sys.exit(1)
+
+        # 按不同的函数计数，同一对函数之间的多处调用只算一次
+        print("\n被调用最多的函数（扇入，不同调用者个数）:")
+        for i, m in enumerate(most_called, 1):
+            print(f"{i:3d}. {m.fan_in:5d}  {m.node.qualified_name}")
+
+        print("\n调用最多的函数（扇出，不同被调用函数个数）:")
+        for i, m in enumerate(most_calling, 1):
+            print(f"{i:3d}. {m.fan_out:5d}  {m.node.qualified_name}")
+
+    finally:
+        analyzer.close()
+
+
+def cmd_path(args):
+    """调用路径查询命令"""
+    if args.output and args.all:
+        print("错误: --output 不能与 --all 一起使用（只能高亮一条路径）")
+        sys.exit(1)
+
+    analyzer = CallGraphAnalyzer(args.database)
+    try:
+        graph = analyzer.load_graph(args.external)
+    finally:
+        analyzer.close()
+
+    try:
+        if args.all:
+            paths = graph.all_paths(args.source, args.target, args.max_length)
+        else:
+            path = graph.path(args.source, args.target)
+            paths = [path] if path else []
+    except ValueError as e:
+        print(f"错误: {e}")
+        sys.exit(1)
+
+    if not paths:
+        print(f"没有从 {args.source} 到 {args.target} 的调用路径")
+        sys.exit(1)
+
+    if args.all:
+        print(f"\n找到 {len(paths)} 条调用路径（最多 {args.max_length} 次调用）:\n")
+    else:
+        print(f"\n最短调用路径（{len(paths[0]) - 1} 次调用）:\n")
+    for i, path in enumerate(paths, 1):
+        print(f"{i}. {' -> '.join(node.name for node in path)}")
+        if args.verbose:
+            for node in path:
+                location = f"{node.file}:{node.line}" if node.file else "external"
+                print(f"   {node.qualified_name} - {location}")
+            print()
+
+    if args.output:
+        # 导出完整调用图，高亮这条路径
+        options = ExportOptions(
+            cluster=not args.no_cluster,
+            highlight_path=[node.id for node in paths[0]],
+        )
+        with open(args.output, "w", encoding="utf-8") as f:
+            f.write(to_dot(graph, options))
+        print(f"已保存到: {args.output}")
+
+
 def cmd_export(args):
     """导出命令"""
     analyzer = CallGraphAnalyzer(args.database)
@@ -327,7 +674,26 @@ def cmd_export(args):
     try:
         print(f"导出调用图为 {args.format} 格式...")
 
-        content = analyzer.export_graph(args.format)
+        check_export_arguments(args)
+        try:
+            content = analyzer.export_graph(
+                args.format,
+                args.external,
+                args.entry,
+                args.depth,
+                export_options(args),
+                args.by_package,
+                args.self_edges,
+                args.merge_names,
+                args.implements,
+                args.filter,
+                args.neighbors,
+                args.leaf,
+                args.transparent,
+            )
+        except (ValueError, RuntimeError) as e:
+            print(f"错误: {e}")
+            sys.exit(1)
 
         if args.output:
             with open(args.output, "w", encoding="utf-8") as f:
@@ -340,6 +706,125 @@ def cmd_export(args):
         analyzer.close()
 
 
+def cmd_watch(args):
+    """监视模式命令"""
+    options = analysis_options(args)
+    if args.fast:
+        analyzer = CallGraphAnalyzerOptimized(args.database, options=options)
+    else:
+        analyzer = CallGraphAnalyzer(args.database, options=options)
+    exporter = EXPORTERS[args.format]
+    check_export_arguments(args)
+    output_options = export_options(args)
+
+    def on_update(graph):
+        timestamp = time.strftime("%H:%M:%S")
+        print(f"[{timestamp}] 分析完成: {len(graph.nodes)} 个节点, {len(graph.edges)} 条边")
+        if not args.output:
+            return
+        # 与 export 命令使用同样的裁剪步骤；入口函数等暂时不存在时
+        # 只报告错误，继续监视（修改代码后在下一次分析时导出）
+        try:
+            graph = prepare_export_graph(
+                graph,
+                args.entry,
+                args.depth,
+                args.by_package,
+                args.self_edges,
+                args.filter,
+                args.neighbors,
+                args.leaf,
+                args.transparent,
+            )
+            content = exporter(graph, output_options)
+        except (ValueError, RuntimeError) as e:
+            print(f"错误: {e}（本次没有导出）")
+            return
+        with open(args.output, "w", encoding="utf-8") as f:
+            f.write(content)
+        print(f"已保存到: {args.output}（{len(graph.nodes)} 个节点, {len(graph.edges)} 条边）")
+
+    print(f"监视项目: {args.project_path}（按 Ctrl+C 退出）")
+    try:
+        watch(
+            analyzer,
+            args.project_path,
+            on_update,
+            interval=args.interval,
+            exclude_dirs=args.exclude.split(",") if args.exclude else None,
+            recursive=not args.no_recursive,
+            external=args.external,
+            merge_names=args.merge_names,
+            implements=args.implements,
+        )
+    except ValueError as e:
+        print(f"错误: {e}")
+        sys.exit(1)
+    except KeyboardInterrupt:
+        print("\n已停止监视")
+    finally:
+        analyzer.close()
+
+
+def cmd_serve(args):
+    """浏览服务命令"""
+    analyzer = CallGraphAnalyzer(args.database)
+    try:
+        graph = analyzer.load_graph(args.external)
+    finally:
+        analyzer.close()
+
+    print(f"已加载调用图: {len(graph.nodes)} 个节点, {len(graph.edges)} 条边")
+    print(f"在浏览器中打开 http://{args.host}:{args.port}/ （按 Ctrl+C 退出）")
+    try:
+        serve(graph, args.host, args.port)
+    except OSError as e:
+        print(f"错误: 无法启动服务器: {e}")
+        sys.exit(1)
+    except KeyboardInterrupt:
+        print("\n已停止服务器")
+
+
+def load_graph_file(path: str, external: str) -> CallGraph:
+    """从数据库文件或 export --format json 导出的 JSON 文件加载调用图"""
+    if not os.path.isfile(path):
+        raise ValueError(f"文件不存在: {path}")
+    if path.endswith(".json"):
+        with open(path, encoding="utf-8") as f:
+            return CallGraph.load_json(f)
+    db = CallGraphDB(path)
+    try:
+        return CallGraph.from_db(db, external)
+    finally:
+        db.close()
+
+
+def cmd_diff(args):
+    """调用图对比命令"""
+    try:
+        old = load_graph_file(args.old, args.external)
+        new = load_graph_file(args.new, args.external)
+    except ValueError as e:
+        print(f"错误: {e}")
+        sys.exit(1)
+
+    result = diff(old, new)
+    content = result.to_dot() if args.format == "dot" else result.to_text()
+
+    if args.output:
+        with open(args.output, "w", encoding="utf-8") as f:
+            f.write(content)
+        print(f"已保存到: {args.output}")
+    elif result.is_empty() and args.format == "text":
+        print("两个调用图没有差异")
+    else:
+        print(content)
+
+    # 与 git diff --exit-code 一样，有差异时返回 1，便于在 CI 中使用
+    if args.exit_code and not result.is_empty():
+        sys.exit(1)
+
+
 def main():
     """主函数"""
     parser = argparse.ArgumentParser(
@@ -360,6 +845,12 @@ def main():
   # 分析项目（排除特定目录）
   python call-graph.py --database myproject.db analyze /path/to/project --exclude "node_modules,build"
   
+  # 按 Windows 平台和 integration 构建标签选择 Go 文件
+  python call-graph.py --database myproject.db analyze /path/to/project --goos windows --tags integration
+  
+  # 只分析根目录下的文件（不递归子目录）
+  python call-graph.py --database myproject.db analyze /path/to/project --no-recursive
+  
   # 查看统计信息
   python call-graph.py --database myproject.db stats
   
@@ -384,11 +875,41 @@ def main():
   # 搜索函数（显示详细信息）
   python call-graph.py --database myproject.db search "calculate" --verbose
   
+  # 检测递归调用
+  python call-graph.py --database myproject.db cycles
+  
+  # 检测从 main 无法到达的函数
+  python call-graph.py --database myproject.db unreachable --init
+  
+  # 列出被调用最多/调用最多的 20 个函数
+  python call-graph.py --database myproject.db metrics --top 20
+  
+  # 查找 Handler 到 Query 的最短调用路径，并导出高亮该路径的调用图
+  python call-graph.py --database myproject.db path Handler Query -o path.dot
+  
   # 导出调用图为 DOT 格式
   python call-graph.py --database myproject.db export --output graph.dot
   
   # 使用 Graphviz 生成可视化图片
   dot -Tpng graph.dot -o graph.png
+  
+  # 只导出 main 及其 3 层以内调用的函数
+  python call-graph.py --database myproject.db export --entry main --depth 3 -o main.dot
+  
+  # 导出为 Mermaid 流程图（可嵌入 Markdown）
+  python call-graph.py --database myproject.db export --format mermaid -o graph.mmd
+  
+  # 导出包级别的依赖图（边上的数字为两个包之间的函数调用对个数）
+  python call-graph.py --database myproject.db export --by-package -o packages.dot
+  
+  # 监视模式：文件保存后自动重新分析并更新 graph.dot
+  python call-graph.py --database myproject.db watch /path/to/project -o graph.dot
+  
+  # 在浏览器中浏览调用图（http://127.0.0.1:8000/）
+  python call-graph.py --database myproject.db serve
+  
+  # 对比两次分析的结果（数据库或 JSON 文件），有差异时返回 1
+  python call-graph.py diff base.db head.db --exit-code
 
 安装依赖:
   pip install -e .
@@ -397,7 +918,8 @@ def main():
   1. --database 参数必须放在子命令之前
   2. 首次使用需要先安装依赖（见上方）
   3. 首次分析建议使用 --clear 清空旧数据
-  4. 默认排除 node_modules, .git, __pycache__ 等目录
+  4. 默认排除 node_modules, vendor, .git, __pycache__ 等目录及所有隐藏目录
+  5. 个别文件解析失败不会中断分析，失败文件会在结束时汇总列出
   
 其他运行方式：
   # 使用 Python 模块方式
@@ -424,6 +946,9 @@ def main():
     analyze_parser.add_argument(
         "--clear", "-c", action="store_true", help="清空现有数据"
     )
+    analyze_parser.add_argument(
+        "--no-recursive", action="store_true", help="只分析项目根目录下的文件，不进入子目录"
+    )
     analyze_parser.add_argument(
         "--fast", "-f", action="store_true", help="使用性能优化模式（多进程+批量操作）"
     )
@@ -441,6 +966,40 @@ def main():
         default=100,
         help="批量插入数据库的大小（默认：100）",
     )
+    analyze_parser.add_argument(
+        "--no-interfaces",
+        action="store_true",
+        help="不把接口方法调用展开到所有实现（Go，适合接口扇出很大的项目）",
+    )
+    analyze_parser.add_argument(
+        "--no-ambiguous",
+        action="store_true",
+        help="接收者类型无法确定时不连到所有同名方法，作为外部调用（Go）",
+    )
+    analyze_parser.add_argument(
+        "--tests",
+        action="store_true",
+        help="同时分析 Go 的测试文件（*_test.go，默认跳过）",
+    )
+    analyze_parser.add_argument(
+        "--strict",
+        action="store_true",
+        help="存在无法确定目标的调用（unresolved）时列出它们并以状态码 1 退出",
+    )
+    analyze_parser.add_argument(
+        "--timeout",
+        type=float,
+        metavar="SECONDS",
+        help="分析超过该时间后放弃，不保存任何结果并以状态码 1 退出",
+    )
+    analyze_parser.add_argument(
+        "--packages",
+        action="append",
+        metavar="PATTERN",
+        help="按 Go 包模式分析（如 ./...，可以指定多次），由 go list 在项目路径中"
+        "展开，需要安装 Go 工具链",
+    )
+    add_build_arguments(analyze_parser)
 
     # query命令
     query_parser = subparsers.add_parser("query", help="查询调用关系")
@@ -463,6 +1022,12 @@ def main():
     query_parser.add_argument(
         "--verbose", "-v", action="store_true", help="显示详细信息（包括完整路径）"
     )
+    query_parser.add_argument(
+        "--merge-names",
+        action="store_true",
+        help="--chain/--fullpath 把不同包中的同名函数当作同一个函数"
+        "（默认按包区分，重名的函数显示限定名）",
+    )
 
     # search命令
     search_parser = subparsers.add_parser("search", help="搜索符号")
@@ -472,14 +1037,182 @@ def main():
     )
 
     # stats命令
-    subparsers.add_parser("stats", help="显示统计信息")
+    stats_parser = subparsers.add_parser("stats", help="显示统计信息")
+    stats_parser.add_argument(
+        "--entry",
+        action="append",
+        help="入口函数名称，可以指定多次；指定时统计从入口无法到达的函数个数",
+    )
+
+    # cycles命令
+    cycles_parser = subparsers.add_parser("cycles", help="检测递归调用环")
+    cycles_parser.add_argument(
+        "--verbose", "-v", action="store_true", help="显示每个函数的位置"
+    )
+
+    # unreachable命令
+    unreachable_parser = subparsers.add_parser(
+        "unreachable", help="检测从入口函数无法到达的函数（死代码）"
+    )
+    unreachable_parser.add_argument(
+        "--entry",
+        action="append",
+        help="入口函数名称，可以指定多次 (默认: main)",
+    )
+    unreachable_parser.add_argument(
+        "--exported",
+        action="store_true",
+        help="把导出的函数也当作入口（Go 中首字母大写的函数；"
+        "其他语言不区分导出，所有函数都会被当作入口）",
+    )
+    unreachable_parser.add_argument(
+        "--init", action="store_true", help="把 Go 的 init 函数也当作入口"
+    )
+
+    # ambiguous命令
+    subparsers.add_parser(
+        "ambiguous", help="列出接收者类型无法确定、连到了多个同名方法的调用（Go）"
+    )
+
+    # metrics命令
+    metrics_parser = subparsers.add_parser(
+        "metrics", help="统计函数的扇入/扇出（不同调用者/被调用函数的个数）"
+    )
+    metrics_parser.add_argument(
+        "--top", type=int, default=10, help="显示前 N 个函数，0 表示全部 (默认: 10)"
+    )
 
     # export命令
     export_parser = subparsers.add_parser("export", help="导出调用图")
     export_parser.add_argument(
-        "--format", "-f", default="dot", choices=["dot"], help="导出格式 (默认: dot)"
+        "--format",
+        "-f",
+        default="dot",
+        choices=sorted(EXPORTERS),
+        help="导出格式 (默认: dot)",
     )
     export_parser.add_argument("--output", "-o", help="输出文件路径")
+    export_parser.add_argument(
+        "--external",
+        default="drop",
+        choices=["drop", "group", "keep"],
+        help="外部调用（标准库、第三方库）的处理方式: drop 丢弃, "
+        "group 合并为一个 <external> 节点, keep 每个外部函数单独保留 (默认: drop)",
+    )
+    add_export_arguments(export_parser)
+
+    # path命令
+    path_parser = subparsers.add_parser("path", help="查找两个函数之间的调用路径")
+    path_parser.add_argument("source", help="起点函数（函数名或限定名）")
+    path_parser.add_argument("target", help="终点函数（函数名或限定名）")
+    path_parser.add_argument(
+        "--all", action="store_true", help="列出所有路径（默认只显示最短的一条）"
+    )
+    path_parser.add_argument(
+        "--max-length",
+        type=int,
+        default=10,
+        help="与 --all 一起使用，路径最多经过几次调用 (默认: 10)",
+    )
+    path_parser.add_argument(
+        "--output", "-o", help="导出高亮了最短路径的完整调用图（DOT 格式）"
+    )
+    path_parser.add_argument(
+        "--external",
+        default="drop",
+        choices=["drop", "group", "keep"],
+        help="外部调用的处理方式，见 export (默认: drop)",
+    )
+    path_parser.add_argument(
+        "--no-cluster", action="store_true", help="DOT 格式下不按包分组"
+    )
+    path_parser.add_argument(
+        "--verbose", "-v", action="store_true", help="显示路径上每个函数的位置"
+    )
+
+    # watch命令
+    watch_parser = subparsers.add_parser(
+        "watch", help="监视项目，文件变化后自动重新分析（并导出）"
+    )
+    watch_parser.add_argument("project_path", help="项目路径")
+    watch_parser.add_argument("--exclude", "-e", help="排除的目录（逗号分隔）")
+    watch_parser.add_argument(
+        "--no-recursive", action="store_true", help="只分析根目录下的文件"
+    )
+    watch_parser.add_argument(
+        "--fast", "-f", action="store_true", help="使用性能优化模式（多进程）"
+    )
+    watch_parser.add_argument(
+        "--no-interfaces", action="store_true", help="不把接口方法调用展开到所有实现"
+    )
+    watch_parser.add_argument(
+        "--no-ambiguous",
+        action="store_true",
+        help="接收者类型无法确定时不连到所有同名方法",
+    )
+    watch_parser.add_argument(
+        "--tests", action="store_true", help="同时分析 Go 的测试文件（*_test.go）"
+    )
+    add_build_arguments(watch_parser)
+    watch_parser.add_argument(
+        "--interval",
+        type=float,
+        default=1.0,
+        help="检查文件变化的间隔秒数 (默认: 1.0)",
+    )
+    watch_parser.add_argument("--output", "-o", help="每次分析后把调用图导出到该文件")
+    watch_parser.add_argument(
+        "--format",
+        default="dot",
+        choices=sorted(EXPORTERS),
+        help="与 --output 一起使用的导出格式 (默认: dot)",
+    )
+    watch_parser.add_argument(
+        "--external",
+        default="drop",
+        choices=["drop", "group", "keep"],
+        help="外部调用的处理方式，含义同 export (默认: drop)",
+    )
+    add_export_arguments(watch_parser)
+
+    # serve命令
+    serve_parser = subparsers.add_parser("serve", help="启动 HTTP 服务器，在浏览器中浏览调用图")
+    serve_parser.add_argument(
+        "--host", default="127.0.0.1", help="监听地址 (默认: 127.0.0.1)"
+    )
+    serve_parser.add_argument(
+        "--port", "-p", type=int, default=8000, help="监听端口 (默认: 8000)"
+    )
+    serve_parser.add_argument(
+        "--external",
+        default="drop",
+        choices=["drop", "group", "keep"],
+        help="外部调用的处理方式，含义同 export (默认: drop)",
+    )
+
+    # diff命令
+    diff_parser = subparsers.add_parser(
+        "diff", help="对比两个调用图（数据库文件或导出的 JSON 文件）"
+    )
+    diff_parser.add_argument("old", help="旧调用图（.db 数据库或 .json 文件）")
+    diff_parser.add_argument("new", help="新调用图（.db 数据库或 .json 文件）")
+    diff_parser.add_argument(
+        "--format",
+        "-f",
+        default="text",
+        choices=["text", "dot"],
+        help="输出格式: text 文本, dot 新增为绿色、删除为红色的 DOT 图 (默认: text)",
+    )
+    diff_parser.add_argument("--output", "-o", help="输出文件路径")
+    diff_parser.add_argument(
+        "--external",
+        default="drop",
+        choices=["drop", "group", "keep"],
+        help="从数据库加载时外部调用的处理方式，含义同 export (默认: drop)",
+    )
+    diff_parser.add_argument(
+        "--exit-code", action="store_true", help="有差异时以状态码 1 退出"
+    )
 
     args = parser.parse_args()
 
@@ -496,8 +1229,26 @@ def main():
         cmd_search(args)
     elif args.command == "stats":
         cmd_stats(args)
+    elif args.command == "cycles":
+        cmd_cycles(args)
+    elif args.command == "unreachable":
+        if not args.entry:
+            args.entry = ["main"]
+        cmd_unreachable(args)
+    elif args.command == "ambiguous":
+        cmd_ambiguous(args)
+    elif args.command == "metrics":
+        cmd_metrics(args)
+    elif args.command == "path":
+        cmd_path(args)
     elif args.command == "export":
         cmd_export(args)
+    elif args.command == "watch":
+        cmd_watch(args)
+    elif args.command == "serve":
+        cmd_serve(args)
+    elif args.command == "diff":
+        cmd_diff(args)
 
 
 if __name__ == "__main__":
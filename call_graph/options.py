@@ -0,0 +1,79 @@
+"""
+分析选项
+"""
+
+from dataclasses import dataclass, field
+from typing import Callable, Dict, List, Optional
+
+
+@dataclass
+class AnalysisOptions:
+    """
+    分析器配置
+
+    会被传递给每个语言解析器；性能优化模式下还会被发送到工作进程，
+    因此只能包含可以被 pickle 的简单字段。
+    """
+
+    # 接口方法调用是否展开为到所有实现的边（Go）
+    # 大型项目中接口调用的扇出可能非常大，可以关闭
+    resolve_interfaces: bool = True
+
+    # 接收者的类型无法确定（range 变量、索引表达式、外部函数的返回值等）时，
+    # 是否把 x.Method() 连到项目中所有同名的方法并标记为 ambiguous（Go）；
+    # 关闭时这类调用与之前一样作为外部调用
+    resolve_ambiguous: bool = True
+
+    # 是否分析 Go 的测试文件（*_test.go）
+    # 分析时 TestXxx、BenchmarkXxx 等测试函数在调用图中作为入口
+    include_tests: bool = False
+
+    # Go 的构建环境，不满足构建约束（文件名后缀、//go:build）的文件会被跳过
+    # goos/goarch 为 None 时使用当前主机（GOOS/GOARCH 环境变量优先）
+    goos: Optional[str] = None
+    goarch: Optional[str] = None
+    # 额外满足的构建标签（如 integration、cgo）
+    build_tags: List[str] = field(default_factory=list)
+
+    # Go 包目录（绝对路径）-> 导入路径，由 go list 得到（见 analyze_packages）
+    # 不在其中的目录按 go.mod 推算导入路径
+    go_package_paths: Dict[str, str] = field(default_factory=dict)
+
+    # 严格模式：分析结束后如果有无法确定目标的调用（unresolved 边），
+    # 抛出 UnresolvedCallsError 列出每一处调用（结果仍然会保存到数据库）
+    strict_resolution: bool = False
+
+
+@dataclass
+class ExportOptions:
+    """导出配置，各导出格式只使用其中适用的字段"""
+
+    # DOT: 按包把函数放进 subgraph cluster_<包> 方框中
+    cluster: bool = True
+
+    # DOT: 在调用边上标注调用次数（不同调用位置的个数，只有一处调用时不标注）
+    call_counts: bool = False
+
+    # DOT: 需要高亮显示的调用路径（按调用顺序排列的节点 ID），
+    # 路径上的函数和调用显示为红色，其他节点和边淡化显示
+    highlight_path: List[str] = field(default_factory=list)
+
+    # DOT/Mermaid/SVG: 节点标签的格式化函数 (Node) -> str，默认显示函数名
+    # （方法为 Type.Method）；内置的格式见 exporters.LABEL_FORMATTERS。
+    # 只影响显示的文字，边仍然通过节点 ID 连接，标签相同的节点不会被合并
+    label: Optional[Callable[..., str]] = None
+
+    # DOT: 按扇入（不同调用者的个数）给函数节点填充颜色，被调用最多的函数
+    # 最显眼；没有调用者的函数（入口、死代码）使用 no_callers_color
+    color_by_fan_in: bool = False
+
+    # DOT: color_by_fan_in 使用的颜色，从扇入最小到最大排列，
+    # 为空时使用 exporters.DOT_FAN_IN_PALETTE
+    fan_in_palette: List[str] = field(default_factory=list)
+
+    # DOT: 没有调用者的函数的颜色，为空时使用 exporters.DOT_NO_CALLERS_COLOR
+    no_callers_color: Optional[str] = None
+
+    # DOT: 把同一接收者类型的方法合并为一个 Mrecord 节点（类型名加方法列表，
+    # 接近 UML 类图），调用边连接到具体方法所在的格子；普通函数仍为单独的节点
+    group_methods: bool = False
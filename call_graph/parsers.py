@@ -5,10 +5,30 @@
 
 import hashlib
 from pathlib import Path
-from typing import Any, Dict, List, Optional
+from typing import Any, Dict, List, Optional, Set
 
 from tree_sitter import Language, Node, Parser
 
+# 支持相对导入和直接运行
+try:
+    from .go_resolver import (
+        GoCallExtractor,
+        GoFileContext,
+        named_type,
+        type_parameter_names,
+    )
+    from .graph import EdgeKind
+    from .options import AnalysisOptions
+except ImportError:
+    from go_resolver import (
+        GoCallExtractor,
+        GoFileContext,
+        named_type,
+        type_parameter_names,
+    )
+    from graph import EdgeKind
+    from options import AnalysisOptions
+
 # 语言配置
 LANGUAGE_CONFIG = {
     "python": {
@@ -77,9 +97,10 @@ LANGUAGE_CONFIG = {
 class LanguageParser:
     """多语言解析器基类"""
 
-    def __init__(self, language_name: str):
+    def __init__(self, language_name: str, options: Optional[AnalysisOptions] = None):
         self.language_name = language_name
         self.config = LANGUAGE_CONFIG[language_name]
+        self.options = options or AnalysisOptions()
         self.language = self._load_language()
         # tree-sitter >= 0.21 使用 Language 包装器
         self.ts_language = (
@@ -88,6 +109,8 @@ class LanguageParser:
             else self.language
         )
         self.parser = Parser(self.ts_language)
+        # 最近一次 parse_file() 发现的语法错误，没有错误时为 None
+        self.syntax_error: Optional[str] = None
 
     def _load_language(self):
         """加载tree-sitter语言"""
@@ -106,15 +129,19 @@ class LanguageParser:
             raise ImportError(f"无法加载{self.language_name}语言解析器: {e}")
 
     def parse_file(self, file_path: str) -> Optional[Node]:
-        """解析文件"""
-        try:
-            with open(file_path, "rb") as f:
-                code = f.read()
-            tree = self.parser.parse(code)
-            return tree.root_node
-        except Exception as e:
-            print(f"解析文件失败 {file_path}: {e}")
-            return None
+        """
+        解析文件
+
+        读取或解析失败时直接抛出异常，由分析器记录到失败文件列表中。
+        tree-sitter 遇到语法错误时不会抛出异常，而是在语法树中插入 ERROR 节点：
+        这时仍然返回（部分正确的）语法树，错误位置记录在 syntax_error 中
+        """
+        with open(file_path, "rb") as f:
+            code = f.read()
+        tree = self.parser.parse(code)
+        root = tree.root_node
+        self.syntax_error = find_syntax_error(root) if root.has_error else None
+        return root
 
     def get_node_text(self, node: Node, source_code: bytes) -> str:
         """获取节点的文本内容"""
@@ -131,6 +158,37 @@ class LanguageParser:
         """提取函数名称（需要子类实现）"""
         raise NotImplementedError
 
+    def build_symbol(
+        self,
+        file_path: str,
+        name: str,
+        kind: str,
+        node: Node,
+        source_code: bytes,
+        container: Optional[str] = None,
+    ) -> Dict[str, Any]:
+        """根据语法树节点构造符号记录"""
+        # 提取签名（声明的第一行）
+        signature = self.get_node_text(node, source_code).split("\n")[0]
+        if len(signature) > 200:
+            signature = signature[:200] + "..."
+
+        return {
+            "id": self.generate_id(file_path, name, node.start_point[0]),
+            "file": file_path,
+            "name": name,
+            "kind": kind,
+            "start_line": node.start_point[0] + 1,
+            "start_column": node.start_point[1],
+            "end_line": node.end_point[0] + 1,
+            "start_byte": node.start_byte,
+            "end_byte": node.end_byte,
+            "container": container,
+            "signature": signature,
+            "language": self.language_name,
+            "is_exported": 1,
+        }
+
     def extract_functions(self, file_path: str) -> List[Dict[str, Any]]:
         """提取文件中的所有函数定义"""
         root = self.parse_file(file_path)
@@ -146,30 +204,15 @@ class LanguageParser:
             if node.type in self.config["function_types"]:
                 func_name = self.extract_function_name(node, source_code)
                 if func_name:
-                    func_id = self.generate_id(
-                        file_path, func_name, node.start_point[0]
-                    )
-
-                    # 提取函数签名
-                    signature = self.get_node_text(node, source_code).split("\n")[0]
-                    if len(signature) > 200:
-                        signature = signature[:200] + "..."
-
                     functions.append(
-                        {
-                            "id": func_id,
-                            "file": file_path,
-                            "name": func_name,
-                            "kind": "function",
-                            "start_line": node.start_point[0] + 1,
-                            "end_line": node.end_point[0] + 1,
-                            "start_byte": node.start_byte,
-                            "end_byte": node.end_byte,
-                            "container": container,
-                            "signature": signature,
-                            "language": self.language_name,
-                            "is_exported": 1,
-                        }
+                        self.build_symbol(
+                            file_path,
+                            func_name,
+                            "function",
+                            node,
+                            source_code,
+                            container,
+                        )
                     )
 
                     # 更新容器名称
@@ -218,10 +261,10 @@ class LanguageParser:
                     caller = find_containing_function(line)
 
                     if caller:
-                        # 尝试匹配被调用的函数
+                        # 尝试匹配被调用的函数（跳过类型等非函数符号）
                         callee_id = None
                         for func in functions:
-                            if func["name"] == call_name:
+                            if func["name"] == call_name and func["kind"] == "function":
                                 callee_id = func["id"]
                                 break
 
@@ -240,6 +283,7 @@ class LanguageParser:
                                 "call_site_line": line,
                                 "call_site_column": node.start_point[1],
                                 "language": self.language_name,
+                                "kind": EdgeKind.DIRECT,
                             }
                         )
 
@@ -257,8 +301,8 @@ class LanguageParser:
 class PythonParser(LanguageParser):
     """Python语言解析器"""
 
-    def __init__(self):
-        super().__init__("python")
+    def __init__(self, options: Optional[AnalysisOptions] = None):
+        super().__init__("python", options)
 
     def extract_function_name(self, node: Node, source_code: bytes) -> Optional[str]:
         for child in node.children:
@@ -284,8 +328,8 @@ class PythonParser(LanguageParser):
 class CParser(LanguageParser):
     """C语言解析器"""
 
-    def __init__(self):
-        super().__init__("c")
+    def __init__(self, options: Optional[AnalysisOptions] = None):
+        super().__init__("c", options)
 
     def extract_function_name(self, node: Node, source_code: bytes) -> Optional[str]:
         declarator = node.child_by_field_name("declarator")
@@ -306,8 +350,8 @@ class CParser(LanguageParser):
 class CppParser(LanguageParser):
     """C++语言解析器"""
 
-    def __init__(self):
-        super().__init__("cpp")
+    def __init__(self, options: Optional[AnalysisOptions] = None):
+        super().__init__("cpp", options)
 
     def extract_function_name(self, node: Node, source_code: bytes) -> Optional[str]:
         declarator = node.child_by_field_name("declarator")
@@ -336,8 +380,8 @@ class CppParser(LanguageParser):
 class JavaParser(LanguageParser):
     """Java语言解析器"""
 
-    def __init__(self):
-        super().__init__("java")
+    def __init__(self, options: Optional[AnalysisOptions] = None):
+        super().__init__("java", options)
 
     def extract_function_name(self, node: Node, source_code: bytes) -> Optional[str]:
         name_node = node.child_by_field_name("name")
@@ -361,8 +405,8 @@ class JavaParser(LanguageParser):
 class RustParser(LanguageParser):
     """Rust语言解析器"""
 
-    def __init__(self):
-        super().__init__("rust")
+    def __init__(self, options: Optional[AnalysisOptions] = None):
+        super().__init__("rust", options)
 
     def extract_function_name(self, node: Node, source_code: bytes) -> Optional[str]:
         name_node = node.child_by_field_name("name")
@@ -384,8 +428,8 @@ class RustParser(LanguageParser):
 class JavaScriptParser(LanguageParser):
     """JavaScript语言解析器"""
 
-    def __init__(self):
-        super().__init__("javascript")
+    def __init__(self, options: Optional[AnalysisOptions] = None):
+        super().__init__("javascript", options)
 
     def extract_function_name(self, node: Node, source_code: bytes) -> Optional[str]:
         name_node = node.child_by_field_name("name")
@@ -416,8 +460,8 @@ class JavaScriptParser(LanguageParser):
 class TypeScriptParser(LanguageParser):
     """TypeScript语言解析器"""
 
-    def __init__(self):
-        super().__init__("typescript")
+    def __init__(self, options: Optional[AnalysisOptions] = None):
+        super().__init__("typescript", options)
 
     def extract_function_name(self, node: Node, source_code: bytes) -> Optional[str]:
         name_node = node.child_by_field_name("name")
@@ -446,10 +490,16 @@ class TypeScriptParser(LanguageParser):
 
 
 class GoParser(LanguageParser):
-    """Go语言解析器"""
+    """
+    Go语言解析器
+
+    除函数和方法外还会提取类型声明（结构体、接口）和包级变量，
+    供第二遍扫描时按静态类型解析方法调用和接口调用、
+    解析通过包级函数变量的调用
+    """
 
-    def __init__(self):
-        super().__init__("go")
+    def __init__(self, options: Optional[AnalysisOptions] = None):
+        super().__init__("go", options)
 
     def extract_function_name(self, node: Node, source_code: bytes) -> Optional[str]:
         name_node = node.child_by_field_name("name")
@@ -458,25 +508,26 @@ class GoParser(LanguageParser):
 
             # 对于方法声明，获取接收者类型
             if node.type == "method_declaration":
-                receiver = node.child_by_field_name("receiver")
+                receiver = self._receiver_type(node, source_code)
                 if receiver:
-                    # 提取接收者类型
-                    for child in receiver.children:
-                        if child.type == "parameter_list":
-                            for param in child.children:
-                                if param.type == "parameter_declaration":
-                                    type_node = param.child_by_field_name("type")
-                                    if type_node:
-                                        type_name = self.get_node_text(
-                                            type_node, source_code
-                                        )
-                                        # 去除指针符号
-                                        type_name = type_name.lstrip("*")
-                                        return f"{type_name}.{func_name}"
+                    # 去除指针符号和泛型类型的类型参数（*Stack[T] -> Stack）
+                    type_name = named_type(receiver) or receiver.lstrip("*")
+                    return f"{type_name}.{func_name}"
 
             return func_name
         return None
 
+    def _receiver_type(self, node: Node, source_code: bytes) -> Optional[str]:
+        """提取方法接收者的类型文本（如 "*User"）"""
+        receiver = node.child_by_field_name("receiver")
+        if receiver:
+            for param in receiver.named_children:
+                if param.type == "parameter_declaration":
+                    type_node = param.child_by_field_name("type")
+                    if type_node:
+                        return self.get_node_text(type_node, source_code)
+        return None
+
     def extract_call_name(self, node: Node, source_code: bytes) -> Optional[str]:
         function_node = node.child_by_field_name("function")
         if function_node:
@@ -489,6 +540,556 @@ class GoParser(LanguageParser):
             return self.get_node_text(function_node, source_code)
         return None
 
+    def extract_functions(self, file_path: str) -> List[Dict[str, Any]]:
+        """提取文件中的函数、方法、类型声明和包级变量"""
+        root = self.parse_file(file_path)
+        if not root:
+            return []
+
+        with open(file_path, "rb") as f:
+            source_code = f.read()
+
+        context = GoFileContext(
+            root, source_code, file_path, self.options.go_package_paths
+        )
+        symbols = []
+
+        for node in root.children:
+            if node.type in self.config["function_types"]:
+                symbol = self._function_symbol(node, source_code, file_path, context)
+                if symbol:
+                    start = len(symbols)
+                    symbols.append(symbol)
+                    self._closure_symbols(
+                        node, symbol, source_code, file_path, context, symbols
+                    )
+                    closures = {s["start_byte"]: s["name"] for s in symbols[start:]}
+                    symbol["extras"]["field_assigns"] = self._field_assignments(
+                        node, source_code, context, closures
+                    )
+            elif node.type == "type_declaration":
+                for spec in node.named_children:
+                    if spec.type in ("type_spec", "type_alias"):
+                        symbol = self._type_symbol(
+                            spec, source_code, file_path, context
+                        )
+                        if symbol:
+                            symbols.append(symbol)
+            elif node.type == "var_declaration":
+                for spec in self._var_specs(node):
+                    symbols.extend(
+                        self._variable_symbols(spec, source_code, file_path, context)
+                    )
+
+        return symbols
+
+    def _function_symbol(
+        self, node: Node, source_code: bytes, file_path: str, context: GoFileContext
+    ) -> Optional[Dict[str, Any]]:
+        func_name = self.extract_function_name(node, source_code)
+        if not func_name:
+            return None
+
+        receiver_text = self._receiver_type(node, source_code)
+        receiver = named_type(receiver_text) if receiver_text else None
+        if receiver and "." in receiver:
+            receiver = None
+
+        symbol = self.build_symbol(
+            file_path, func_name, "function", node, source_code, receiver
+        )
+        short_name = func_name.rsplit(".", 1)[-1]
+        symbol["package"] = context.package
+        symbol["receiver"] = receiver
+        symbol["is_exported"] = int(short_name[:1].isupper())
+        type_params = type_parameter_names(node, source_code)
+        symbol["extras"] = {
+            "package_name": context.package_name,
+            "pointer_receiver": bool(receiver_text and receiver_text.startswith("*")),
+            "params": self._count_params(node.child_by_field_name("parameters")),
+            "results": [
+                context.type_ref(text, type_params)
+                for text in self._result_types(node, source_code)
+            ],
+            "type_params": sorted(type_params),
+            "assigns": self._package_assignments(node, source_code, context),
+        }
+        return symbol
+
+    def _package_assignments(
+        self, node: Node, source_code: bytes, context: GoFileContext
+    ) -> List[List[str]]:
+        """
+        函数（包括其中的匿名函数）中用 = 赋值的包级标识符 [[包标识, 名称], ...]
+
+        用于判断包级函数变量是否在运行时被重新赋值。不区分作用域：
+        函数中任何位置声明过的名称都当作局部变量，pkg.Var = ... 按导入路径记录
+        """
+        declared: Set[str] = set()
+        targets: List[Node] = []
+
+        def visit(current: Node):
+            if current.type in (
+                "parameter_declaration",
+                "variadic_parameter_declaration",
+                "var_spec",
+                "const_spec",
+            ):
+                for name_node in current.children_by_field_name("name"):
+                    declared.add(self.get_node_text(name_node, source_code))
+            elif current.type in (
+                "short_var_declaration",
+                "range_clause",
+                "receive_statement",
+            ):
+                left = current.child_by_field_name("left")
+                if left is not None:
+                    for child in left.named_children:
+                        declared.add(self.get_node_text(child, source_code))
+            elif current.type == "type_switch_statement":
+                for alias in current.children_by_field_name("alias"):
+                    declared.add(self.get_node_text(alias, source_code))
+            elif current.type == "assignment_statement":
+                left = current.child_by_field_name("left")
+                if left is not None:
+                    targets.extend(left.named_children)
+            for child in current.children:
+                visit(child)
+
+        visit(node)
+        assigned = set()
+        for target in targets:
+            if target.type == "identifier":
+                name = self.get_node_text(target, source_code)
+                if name not in declared:
+                    assigned.add((context.package, name))
+            elif target.type == "selector_expression":
+                operand = target.child_by_field_name("operand")
+                field = target.child_by_field_name("field")
+                if operand is None or field is None or operand.type != "identifier":
+                    continue
+                alias = self.get_node_text(operand, source_code)
+                if alias in context.imports and alias not in declared:
+                    assigned.add(
+                        (context.imports[alias], self.get_node_text(field, source_code))
+                    )
+        return [list(item) for item in sorted(assigned)]
+
+    def _field_assignments(
+        self,
+        node: Node,
+        source_code: bytes,
+        context: GoFileContext,
+        closures: Dict[int, str],
+    ) -> List[list]:
+        """
+        函数（包括其中的匿名函数）中给结构体字段赋的值
+        [[类型的包标识, 类型名, 字段名, 值], ...]
+
+        识别复合字面量 T{f: v}、&T{f: v}，以及 x.f = v（x 的类型来自接收者、参数、
+        x := T{...}、x := &T{...} 和 var x T）。值为 ["func", 包标识, 函数名]
+        （函数名、pkg.Func、匿名函数）、["method", 包标识, 类型名, 方法名]
+        （x.Method 方法值）或 ["unknown"]（其他可能是函数的表达式）；
+        字符串、数字等明显不是函数的值不记录。与 _package_assignments 一样不区分作用域。
+        closures 为匿名函数的起始字节 -> 匿名函数名。
+        """
+        type_params = type_parameter_names(node, source_code)
+        # 局部变量名 -> 类型引用（类型未知时为 None）
+        env: Dict[str, Any] = {}
+        result: List[list] = []
+
+        def text(current: Node) -> str:
+            return self.get_node_text(current, source_code)
+
+        def literal_type(value: Node):
+            if value.type == "unary_expression":
+                operand = value.child_by_field_name("operand")
+                if operand is None or operand.type != "composite_literal":
+                    return None
+                value = operand
+            if value.type != "composite_literal":
+                return None
+            type_node = value.child_by_field_name("type")
+            if type_node is None:
+                return None
+            return context.type_ref(text(type_node), type_params)
+
+        def record(ref, field: str, value: Node):
+            if ref is None or not ref[0]:
+                return
+            classified = self._field_value(value, source_code, context, env, closures)
+            if classified is not None:
+                result.append([ref[0], ref[1], field, classified])
+
+        def visit(current: Node):
+            if current.type in ("parameter_declaration", "var_spec"):
+                type_node = current.child_by_field_name("type")
+                ref = (
+                    context.type_ref(text(type_node), type_params)
+                    if type_node is not None
+                    else None
+                )
+                for name_node in current.children_by_field_name("name"):
+                    env[text(name_node)] = ref
+            elif current.type == "short_var_declaration":
+                left = current.child_by_field_name("left")
+                right = current.child_by_field_name("right")
+                names = left.named_children if left is not None else []
+                values = right.named_children if right is not None else []
+                for i, name_node in enumerate(names):
+                    same_count = len(values) == len(names)
+                    env[text(name_node)] = (
+                        literal_type(values[i]) if same_count else None
+                    )
+            elif current.type == "assignment_statement":
+                left = current.child_by_field_name("left")
+                right = current.child_by_field_name("right")
+                targets = left.named_children if left is not None else []
+                values = right.named_children if right is not None else []
+                if len(targets) == len(values):
+                    for target, value in zip(targets, values):
+                        if target.type != "selector_expression":
+                            continue
+                        operand = target.child_by_field_name("operand")
+                        field = target.child_by_field_name("field")
+                        if operand is not None and field is not None:
+                            record(env.get(text(operand)), text(field), value)
+            elif current.type == "composite_literal":
+                ref = literal_type(current)
+                body = current.child_by_field_name("body")
+                for element in body.named_children if body is not None else []:
+                    if element.type != "keyed_element":
+                        continue
+                    parts = [
+                        child.named_children[0]
+                        if child.type == "literal_element" and child.named_children
+                        else child
+                        for child in element.named_children
+                    ]
+                    if len(parts) == 2 and parts[0].type in (
+                        "identifier",
+                        "field_identifier",
+                    ):
+                        record(ref, text(parts[0]), parts[1])
+            for child in current.children:
+                visit(child)
+
+        visit(node)
+        return result
+
+    def _field_value(
+        self,
+        value: Node,
+        source_code: bytes,
+        context: GoFileContext,
+        env: Dict[str, Any],
+        closures: Dict[int, str],
+    ) -> Optional[list]:
+        """给字段赋的值，见 _field_assignments；明显不是函数的值返回 None"""
+        if value.type == "parenthesized_expression" and value.named_children:
+            return self._field_value(
+                value.named_children[0], source_code, context, env, closures
+            )
+        if value.type == "func_literal":
+            name = closures.get(value.start_byte)
+            return ["func", context.package, name] if name else ["unknown"]
+        if value.type == "identifier":
+            name = self.get_node_text(value, source_code)
+            if name in ("nil", "true", "false"):
+                return None
+            if name in env:
+                # 局部变量或参数中保存的函数值无法确定
+                return ["unknown"]
+            return ["func", context.package, name]
+        if value.type == "selector_expression":
+            operand = value.child_by_field_name("operand")
+            field = value.child_by_field_name("field")
+            if operand is None or field is None or operand.type != "identifier":
+                return ["unknown"]
+            alias = self.get_node_text(operand, source_code)
+            name = self.get_node_text(field, source_code)
+            if alias in env:
+                ref = env[alias]
+                return ["method", ref[0], ref[1], name] if ref else ["unknown"]
+            if alias in context.imports:
+                return ["func", context.imports[alias], name]
+            return ["unknown"]
+        if value.type in (
+            "call_expression",
+            "index_expression",
+            "type_assertion_expression",
+        ):
+            return ["unknown"]
+        return None
+
+    def _closure_symbols(
+        self,
+        node: Node,
+        parent: Dict[str, Any],
+        source_code: bytes,
+        file_path: str,
+        context: GoFileContext,
+        symbols: List[Dict[str, Any]],
+    ):
+        """
+        为函数体中的匿名函数生成符号
+
+        匿名函数按在外层函数中出现的顺序命名为 外层函数名$func1、$func2 ……，
+        嵌套的匿名函数在其直接外层匿名函数的名称上继续编号
+        （如 main$func1$func1）
+        """
+        body = node.child_by_field_name("body")
+        if body is None:
+            return
+        for index, literal in enumerate(self._func_literals(body), 1):
+            name = f"{parent['name']}$func{index}"
+            symbol = self.build_symbol(
+                file_path, name, "function", literal, source_code, parent["name"]
+            )
+            symbol["package"] = context.package
+            symbol["receiver"] = None
+            symbol["is_exported"] = 0
+            symbol["extras"] = {
+                "package_name": context.package_name,
+                "closure_of": parent["id"],
+                "params": self._count_params(literal.child_by_field_name("parameters")),
+                "results": [
+                    context.type_ref(text)
+                    for text in self._result_types(literal, source_code)
+                ],
+            }
+            symbols.append(symbol)
+            self._closure_symbols(
+                literal, symbol, source_code, file_path, context, symbols
+            )
+
+    def _func_literals(self, node: Node) -> List[Node]:
+        """node 下最外层的匿名函数（不进入匿名函数内部），按源码顺序"""
+        literals = []
+        for child in node.children:
+            if child.type == "func_literal":
+                literals.append(child)
+            else:
+                literals.extend(self._func_literals(child))
+        return literals
+
+    def _count_params(self, parameter_list: Optional[Node]) -> int:
+        """参数个数（a, b int 记为 2 个）"""
+        if parameter_list is None:
+            return 0
+        count = 0
+        for declaration in parameter_list.named_children:
+            if declaration.type in (
+                "parameter_declaration",
+                "variadic_parameter_declaration",
+            ):
+                count += max(1, len(declaration.children_by_field_name("name")))
+        return count
+
+    def _result_types(self, node: Node, source_code: bytes) -> List[str]:
+        """返回值类型文本列表"""
+        result = node.child_by_field_name("result")
+        if result is None:
+            return []
+        if result.type != "parameter_list":
+            return [self.get_node_text(result, source_code)]
+
+        types = []
+        for declaration in result.named_children:
+            if declaration.type != "parameter_declaration":
+                continue
+            type_node = declaration.child_by_field_name("type")
+            text = self.get_node_text(type_node, source_code) if type_node else ""
+            count = max(1, len(declaration.children_by_field_name("name")))
+            types.extend([text] * count)
+        return types
+
+    def _var_specs(self, node: Node) -> List[Node]:
+        """var 声明中的 var_spec（var (...) 分组在新版语法中包在 var_spec_list 中）"""
+        specs = []
+        for child in node.named_children:
+            if child.type == "var_spec":
+                specs.append(child)
+            elif child.type == "var_spec_list":
+                specs.extend(self._var_specs(child))
+        return specs
+
+    def _variable_symbols(
+        self, spec: Node, source_code: bytes, file_path: str, context: GoFileContext
+    ) -> List[Dict[str, Any]]:
+        """
+        包级变量的符号
+
+        初始值是函数名（var f = doWork、var f = pkg.Func）时记录为 func_ref
+        [包标识, 名称]，调用 f() 时解析到该函数（或者该名称指向的另一个包级变量）
+        """
+        names = spec.children_by_field_name("name")
+        type_node = spec.child_by_field_name("type")
+        value = spec.child_by_field_name("value")
+        values = value.named_children if value is not None else []
+
+        symbols = []
+        for i, name_node in enumerate(names):
+            name = self.get_node_text(name_node, source_code)
+            if name == "_":
+                continue
+            symbol = self.build_symbol(file_path, name, "variable", spec, source_code)
+            symbol["package"] = context.package
+            symbol["is_exported"] = int(name[:1].isupper())
+            func_ref = None
+            if len(values) == len(names):
+                func_ref = self._func_reference(values[i], source_code, context)
+            symbol["extras"] = {
+                "package_name": context.package_name,
+                "type": (
+                    self.get_node_text(type_node, source_code) if type_node else None
+                ),
+                "func_ref": func_ref,
+            }
+            symbols.append(symbol)
+        return symbols
+
+    def _func_reference(
+        self, node: Node, source_code: bytes, context: GoFileContext
+    ) -> Optional[List[str]]:
+        """可能是函数名的表达式（doWork、pkg.Func）对应的 [包标识, 名称]"""
+        if node.type == "parenthesized_expression" and node.named_children:
+            return self._func_reference(node.named_children[0], source_code, context)
+        if node.type == "identifier":
+            return [context.package, self.get_node_text(node, source_code)]
+        if node.type == "selector_expression":
+            operand = node.child_by_field_name("operand")
+            field = node.child_by_field_name("field")
+            if operand is not None and field is not None:
+                path = context.imports.get(self.get_node_text(operand, source_code))
+                if path:
+                    return [path, self.get_node_text(field, source_code)]
+        return None
+
+    def _type_symbol(
+        self, spec: Node, source_code: bytes, file_path: str, context: GoFileContext
+    ) -> Optional[Dict[str, Any]]:
+        name_node = spec.child_by_field_name("name")
+        type_node = spec.child_by_field_name("type")
+        if name_node is None or type_node is None:
+            return None
+        type_name = self.get_node_text(name_node, source_code)
+
+        if type_node.type == "struct_type":
+            kind = "struct"
+            extras = {
+                "fields": self._struct_fields(
+                    type_node,
+                    source_code,
+                    context,
+                    type_parameter_names(spec, source_code),
+                )
+            }
+        elif type_node.type == "interface_type":
+            kind = "interface"
+            methods, embedded = self._interface_elements(
+                type_node, source_code, context
+            )
+            extras = {"methods": methods, "embedded": embedded}
+        else:
+            kind = "type"
+            extras = {"underlying": self.get_node_text(type_node, source_code)}
+
+        symbol = self.build_symbol(file_path, type_name, kind, spec, source_code)
+        symbol["package"] = context.package
+        symbol["is_exported"] = int(type_name[:1].isupper())
+        extras["package_name"] = context.package_name
+        extras["type_params"] = sorted(type_parameter_names(spec, source_code))
+        symbol["extras"] = extras
+        return symbol
+
+    def _struct_fields(
+        self,
+        struct_type: Node,
+        source_code: bytes,
+        context: GoFileContext,
+        type_params: Set[str],
+    ) -> Dict[str, Dict[str, Any]]:
+        """
+        结构体字段 {字段名: {"type": 类型文本, "ref": 类型引用}}
+
+        嵌入字段以类型名作为字段名，并带有 "embedded" 标记；
+        类型为泛型类型参数的字段没有类型引用
+        """
+        fields = {}
+        for field_list in struct_type.named_children:
+            if field_list.type != "field_declaration_list":
+                continue
+            for declaration in field_list.named_children:
+                if declaration.type != "field_declaration":
+                    continue
+                type_node = declaration.child_by_field_name("type")
+                if type_node is None:
+                    continue
+                type_text = self.get_node_text(type_node, source_code)
+                names = declaration.children_by_field_name("name")
+                for name_node in names:
+                    fields[self.get_node_text(name_node, source_code)] = {
+                        "type": type_text,
+                        "ref": context.type_ref(type_text, type_params),
+                    }
+                if not names:
+                    pointer = any(child.type == "*" for child in declaration.children)
+                    embedded_name = (named_type(type_text) or type_text).split(".")[-1]
+                    fields[embedded_name] = {
+                        "type": ("*" if pointer else "") + type_text,
+                        "ref": context.type_ref(type_text),
+                        "embedded": True,
+                    }
+        return fields
+
+    def _interface_elements(
+        self, interface_type: Node, source_code: bytes, context: GoFileContext
+    ):
+        """接口的方法 {方法名: [参数个数, 返回值个数]} 和嵌入的接口引用列表"""
+        methods = {}
+        embedded = []
+        for element in interface_type.named_children:
+            if element.type == "method_spec_list":
+                # 旧版本语法把方法列表包在一层节点中
+                sub_methods, sub_embedded = self._interface_elements(
+                    element, source_code, context
+                )
+                methods.update(sub_methods)
+                embedded.extend(sub_embedded)
+            elif element.type in ("method_elem", "method_spec"):
+                name_node = element.child_by_field_name("name")
+                if name_node is None:
+                    continue
+                methods[self.get_node_text(name_node, source_code)] = [
+                    self._count_params(element.child_by_field_name("parameters")),
+                    len(self._result_types(element, source_code)),
+                ]
+            elif element.type in (
+                "type_elem",
+                "constraint_elem",
+                "interface_type_name",
+                "type_identifier",
+                "qualified_type",
+            ):
+                ref = context.type_ref(self.get_node_text(element, source_code))
+                if ref:
+                    embedded.append(ref)
+        return methods, embedded
+
+    def extract_calls(
+        self, file_path: str, functions: List[Dict[str, Any]]
+    ) -> List[Dict[str, Any]]:
+        """提取函数调用关系（按静态类型解析方法和接口调用）"""
+        root = self.parse_file(file_path)
+        if not root:
+            return []
+
+        with open(file_path, "rb") as f:
+            source_code = f.read()
+
+        return GoCallExtractor(self, file_path, root, source_code, functions).extract()
+
 
 # 解析器工厂
 PARSER_CLASSES = {
@@ -503,12 +1104,27 @@ PARSER_CLASSES = {
 }
 
 
-def get_parser(language: str) -> LanguageParser:
+def find_syntax_error(root: Node) -> str:
+    """语法树中第一个 ERROR 节点或缺失节点的位置（行号和列号从 1 开始）"""
+    stack = [root]
+    while stack:
+        node = stack.pop()
+        if node.type == "ERROR" or node.is_missing:
+            line, column = node.start_point
+            return f"第 {line + 1} 行第 {column + 1} 列有语法错误"
+        # 只进入包含错误的子树，按源码顺序找到第一个错误
+        stack.extend(child for child in reversed(node.children) if child.has_error)
+    return "语法错误"
+
+
+def get_parser(
+    language: str, options: Optional[AnalysisOptions] = None
+) -> LanguageParser:
     """获取指定语言的解析器"""
     parser_class = PARSER_CLASSES.get(language)
     if not parser_class:
         raise ValueError(f"不支持的语言: {language}")
-    return parser_class()
+    return parser_class(options)
 
 
 def detect_language(file_path: str) -> Optional[str]:
@@ -0,0 +1,349 @@
+"""
+调用图浏览服务
+内置的 HTTP 服务器：在浏览器中搜索函数，点击节点逐步展开调用者/被调用者
+"""
+
+import json
+from collections import defaultdict
+from dataclasses import asdict
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+from typing import Any, Dict, List, Tuple
+from urllib.parse import parse_qs, urlparse
+
+# 支持相对导入和直接运行
+try:
+    from .exporters import to_dot
+    from .graph import CallGraph, Node
+except ImportError:
+    from exporters import to_dot
+    from graph import CallGraph, Node
+
+# 搜索接口最多返回的函数个数
+SEARCH_LIMIT = 50
+
+
+class CallGraphServer(ThreadingHTTPServer):
+    """
+    提供调用图数据和浏览页面的 HTTP 服务器
+
+    - /                       浏览页面
+    - /graph.json             完整调用图（与 export --format json 相同）
+    - /graph.dot              完整调用图（DOT）
+    - /api/search?q=关键字    按名称搜索函数
+    - /api/neighbors?id=节点  函数的直接调用者和被调用者
+
+    可以通过 set_graph() 替换正在提供的调用图（例如配合监视模式）。
+    """
+
+    daemon_threads = True
+
+    def __init__(self, address: Tuple[str, int], graph: CallGraph):
+        super().__init__(address, CallGraphRequestHandler)
+        self.set_graph(graph)
+
+    def set_graph(self, graph: CallGraph):
+        # (调用者, 被调用者) -> 边类型列表，查询邻居时附带边的类型
+        kinds: Dict[Tuple[str, str], List[str]] = defaultdict(list)
+        for edge in graph.sorted_edges():
+            kinds[(edge.caller, edge.callee)].append(edge.kind)
+        # 一次性替换，处理中的请求继续使用旧的调用图
+        self.state = (graph, dict(kinds))
+
+    def search(self, query: str) -> List[Node]:
+        """按名称搜索：先精确匹配（规则见 CallGraph.find），再按子串匹配"""
+        graph, _ = self.state
+        query = query.strip()
+        if not query:
+            return []
+        result = graph.find(query)
+        seen = {node.id for node in result}
+        lowered = query.lower()
+        for node in graph.sorted_nodes():
+            if len(result) >= SEARCH_LIMIT:
+                break
+            if node.id not in seen and lowered in node.qualified_name.lower():
+                result.append(node)
+        return result[:SEARCH_LIMIT]
+
+    def neighbors(self, node_id: str) -> Dict[str, Any]:
+        """节点本身、直接调用者、直接被调用者以及它们之间的边"""
+        graph, kinds = self.state
+        node = graph.nodes.get(node_id)
+        if node is None:
+            raise KeyError(node_id)
+        callers = graph.callers_of(node_id)
+        callees = graph.callees_of(node_id)
+
+        edges = []
+        for caller in callers:
+            for kind in kinds.get((caller.id, node_id), []):
+                edges.append({"from": caller.id, "to": node_id, "kind": kind})
+        for callee in callees:
+            # 自递归的边已经在调用者中出现
+            if callee.id == node_id:
+                continue
+            for kind in kinds.get((node_id, callee.id), []):
+                edges.append({"from": node_id, "to": callee.id, "kind": kind})
+
+        return {
+            "node": _node_json(node),
+            "callers": [_node_json(n) for n in callers],
+            "callees": [_node_json(n) for n in callees],
+            "edges": edges,
+        }
+
+
+class CallGraphRequestHandler(BaseHTTPRequestHandler):
+    """只处理 GET 请求，所有响应都是 UTF-8 文本"""
+
+    server: CallGraphServer
+
+    def do_GET(self):
+        url = urlparse(self.path)
+        params = parse_qs(url.query)
+        graph, _ = self.server.state
+
+        if url.path in ("/", "/index.html"):
+            self._send(200, "text/html", INDEX_HTML)
+        elif url.path == "/graph.json":
+            self._send(200, "application/json", graph.to_json())
+        elif url.path == "/graph.dot":
+            self._send(200, "text/vnd.graphviz", to_dot(graph))
+        elif url.path == "/api/search":
+            nodes = self.server.search(params.get("q", [""])[0])
+            self._send_json(200, [_node_json(node) for node in nodes])
+        elif url.path == "/api/neighbors":
+            node_id = params.get("id", [""])[0]
+            try:
+                self._send_json(200, self.server.neighbors(node_id))
+            except KeyError:
+                self._send_json(404, {"error": f"找不到节点: {node_id}"})
+        else:
+            self._send_json(404, {"error": f"未知路径: {url.path}"})
+
+    def _send_json(self, status: int, data: Any):
+        self._send(status, "application/json", json.dumps(data, ensure_ascii=False))
+
+    def _send(self, status: int, content_type: str, body: str):
+        payload = body.encode("utf-8")
+        self.send_response(status)
+        self.send_header("Content-Type", f"{content_type}; charset=utf-8")
+        self.send_header("Content-Length", str(len(payload)))
+        self.end_headers()
+        self.wfile.write(payload)
+
+    def log_message(self, format, *args):
+        # 默认的访问日志输出到 stderr，浏览时每次点击都会产生多行，这里关闭
+        pass
+
+
+def _node_json(node: Node) -> Dict[str, Any]:
+    data = asdict(node)
+    data["qualified_name"] = node.qualified_name
+    return data
+
+
+def serve(graph: CallGraph, host: str = "127.0.0.1", port: int = 8000):
+    """启动服务器并一直运行，直到 KeyboardInterrupt"""
+    with CallGraphServer((host, port), graph) as server:
+        server.serve_forever()
+
+
+# 浏览页面：不依赖外部脚本，按调用层次分列布局，
+# 调用者在左、被调用者在右，点击节点加载它的直接调用者和被调用者
+INDEX_HTML = """<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>调用图浏览</title>
+<style>
+  body { margin: 0; font-family: Arial, sans-serif; font-size: 13px;
+         display: flex; height: 100vh; }
+  #sidebar { width: 300px; padding: 12px; border-right: 1px solid #ddd;
+             overflow: auto; }
+  #sidebar input { width: 100%; box-sizing: border-box; padding: 6px; }
+  #results div { padding: 4px; cursor: pointer; border-bottom: 1px solid #eee; }
+  #results div:hover { background: #eef4ff; }
+  #info { margin-top: 12px; color: #333; word-break: break-all; }
+  #canvas { flex: 1; overflow: auto; }
+  .node rect { fill: #fff; stroke: #555; rx: 4; }
+  .node.expanded rect { fill: #eef4ff; }
+  .node.selected rect { stroke: #d33; stroke-width: 2; }
+  .node.external rect { stroke: #999; stroke-dasharray: 4 2; }
+  .node { cursor: pointer; }
+  .edge { fill: none; stroke: #888; }
+  .edge.interface { stroke: blue; stroke-dasharray: 6 3; }
+  .edge.go { stroke-width: 2.5; }
+  .edge.defer { stroke-dasharray: 6 3; }
+  .edge.ambiguous { stroke: purple; stroke-dasharray: 6 3; }
+</style>
+</head>
+<body>
+<div id="sidebar">
+  <input id="search" placeholder="搜索函数（名称或限定名）" autofocus>
+  <div id="results"></div>
+  <div id="info">点击节点展开它的调用者（左）和被调用者（右），
+    双击节点以它为中心重新开始。
+    原始数据：<a href="graph.json">graph.json</a> · <a href="graph.dot">graph.dot</a></div>
+</div>
+<div id="canvas"><svg id="svg" xmlns="http://www.w3.org/2000/svg"></svg></div>
+<script>
+const NODE_W = 220, NODE_H = 28, COL_GAP = 80, ROW_GAP = 14;
+let nodes = new Map();   // id -> {node, rank, expanded}
+let edges = new Map();   // "from|to|kind" -> edge
+let selected = null;
+
+async function getJSON(url) {
+  const response = await fetch(url);
+  if (!response.ok) throw new Error((await response.json()).error);
+  return response.json();
+}
+
+function addNode(node, rank) {
+  if (!nodes.has(node.id)) nodes.set(node.id, {node, rank, expanded: false});
+}
+
+async function expand(id) {
+  const data = await getJSON("api/neighbors?id=" + encodeURIComponent(id));
+  const entry = nodes.get(id);
+  data.callers.forEach(n => addNode(n, entry.rank - 1));
+  data.callees.forEach(n => addNode(n, entry.rank + 1));
+  data.edges.forEach(e => edges.set(e.from + "|" + e.to + "|" + e.kind, e));
+  entry.expanded = true;
+  select(id);
+}
+
+async function focus(node) {
+  nodes = new Map();
+  edges = new Map();
+  addNode(node, 0);
+  await expand(node.id);
+}
+
+function select(id) {
+  selected = id;
+  const n = nodes.get(id).node;
+  const info = document.getElementById("info");
+  info.textContent = "";
+  for (const line of [n.qualified_name, n.external ? "外部函数" :
+      (n.file || "") + ":" + (n.line || "?")]) {
+    const div = document.createElement("div");
+    div.textContent = line;
+    info.appendChild(div);
+  }
+  render();
+}
+
+function svgElement(tag, attributes) {
+  const element = document.createElementNS("http://www.w3.org/2000/svg", tag);
+  for (const [key, value] of Object.entries(attributes)) {
+    element.setAttribute(key, value);
+  }
+  return element;
+}
+
+function render() {
+  const svg = document.getElementById("svg");
+  svg.textContent = "";
+  const defs = svgElement("defs", {});
+  const marker = svgElement("marker", {
+    id: "arrow", viewBox: "0 0 10 10", refX: 10, refY: 5,
+    markerWidth: 6, markerHeight: 6, orient: "auto-start-reverse",
+  });
+  marker.appendChild(svgElement("path", {d: "M 0 0 L 10 5 L 0 10 z", fill: "#888"}));
+  defs.appendChild(marker);
+  svg.appendChild(defs);
+
+  // 每一列（调用层次）内按名称排序
+  const columns = new Map();
+  for (const entry of nodes.values()) {
+    if (!columns.has(entry.rank)) columns.set(entry.rank, []);
+    columns.get(entry.rank).push(entry);
+  }
+  const ranks = [...columns.keys()].sort((a, b) => a - b);
+  const position = new Map();
+  let height = 0;
+  ranks.forEach((rank, column) => {
+    const entries = columns.get(rank);
+    entries.sort((a, b) => a.node.qualified_name.localeCompare(b.node.qualified_name));
+    entries.forEach((entry, row) => {
+      position.set(entry.node.id, {
+        x: 20 + column * (NODE_W + COL_GAP),
+        y: 20 + row * (NODE_H + ROW_GAP),
+      });
+    });
+    height = Math.max(height, 40 + entries.length * (NODE_H + ROW_GAP));
+  });
+  svg.setAttribute("width", 40 + ranks.length * (NODE_W + COL_GAP));
+  svg.setAttribute("height", height);
+
+  for (const edge of edges.values()) {
+    const from = position.get(edge.from), to = position.get(edge.to);
+    if (!from || !to) continue;
+    let d;
+    if (edge.from === edge.to) {
+      // 自递归画成节点右侧的小环
+      const x = from.x + NODE_W, y = from.y + NODE_H / 2;
+      d = `M ${x} ${y - 6} C ${x + 30} ${y - 20}, ${x + 30} ${y + 20}, ${x} ${y + 6}`;
+    } else {
+      const x1 = from.x + NODE_W, y1 = from.y + NODE_H / 2;
+      const x2 = to.x, y2 = to.y + NODE_H / 2;
+      const bend = Math.max(40, Math.abs(x2 - x1) / 2);
+      d = `M ${x1} ${y1} C ${x1 + bend} ${y1}, ${x2 - bend} ${y2}, ${x2} ${y2}`;
+    }
+    const path = svgElement("path", {
+      d, class: "edge " + edge.kind, "marker-end": "url(#arrow)",
+    });
+    const title = svgElement("title", {});
+    title.textContent = edge.kind;
+    path.appendChild(title);
+    svg.appendChild(path);
+  }
+
+  for (const entry of nodes.values()) {
+    const p = position.get(entry.node.id);
+    const classes = ["node"];
+    if (entry.expanded) classes.push("expanded");
+    if (entry.node.external) classes.push("external");
+    if (entry.node.id === selected) classes.push("selected");
+    const group = svgElement("g", {
+      class: classes.join(" "), transform: `translate(${p.x},${p.y})`,
+    });
+    group.appendChild(svgElement("rect", {width: NODE_W, height: NODE_H}));
+    const text = svgElement("text", {x: 8, y: NODE_H / 2 + 4});
+    const name = entry.node.name;
+    text.textContent = name.length > 30 ? name.slice(0, 29) + "…" : name;
+    group.appendChild(text);
+    const title = svgElement("title", {});
+    title.textContent = entry.node.qualified_name;
+    group.appendChild(title);
+    group.addEventListener("click", () => {
+      if (entry.expanded) select(entry.node.id);
+      else expand(entry.node.id);
+    });
+    group.addEventListener("dblclick", () => focus(entry.node));
+    svg.appendChild(group);
+  }
+}
+
+let searchTimer = null;
+document.getElementById("search").addEventListener("input", event => {
+  clearTimeout(searchTimer);
+  searchTimer = setTimeout(async () => {
+    const results = document.getElementById("results");
+    const query = encodeURIComponent(event.target.value);
+    const found = await getJSON("api/search?q=" + query);
+    results.textContent = "";
+    for (const node of found) {
+      const div = document.createElement("div");
+      div.textContent = node.qualified_name;
+      div.title = node.external ? "外部函数" : (node.file || "") + ":" + (node.line || "?");
+      div.addEventListener("click", () => focus(node));
+      results.appendChild(div);
+    }
+  }, 200);
+});
+</script>
+</body>
+</html>
+"""
@@ -0,0 +1,137 @@
+"""
+监视模式
+轮询项目中源文件的修改时间，文件变化后增量更新数据库并回调新的调用图
+"""
+
+import os
+import time
+from contextlib import redirect_stdout
+from pathlib import Path
+from typing import Callable, Dict, List, Optional, Tuple
+
+# 支持相对导入和直接运行
+try:
+    from .analyzer import DEFAULT_EXCLUDE_DIRS, collect_source_files, print_errors
+    from .graph import CallGraph, ExternalMode
+except ImportError:
+    from analyzer import DEFAULT_EXCLUDE_DIRS, collect_source_files, print_errors
+    from graph import CallGraph, ExternalMode
+
+# 文件路径 -> (修改时间纳秒, 文件大小)
+FileState = Dict[str, Tuple[int, int]]
+
+
+def scan_files(
+    project_path: Path,
+    exclude_dirs: List[str],
+    recursive: bool = True,
+    include_tests: bool = False,
+) -> FileState:
+    """
+    记录所有源代码文件的修改时间和大小
+
+    不按 Go 构建约束过滤，修改 //go:build 行使文件参与或退出构建时也能被发现
+    """
+    state: FileState = {}
+    for file_path in collect_source_files(
+        project_path, exclude_dirs, recursive, include_tests
+    ):
+        try:
+            stat = os.stat(file_path)
+        except OSError:
+            # 扫描期间被删除的文件
+            continue
+        state[file_path] = (stat.st_mtime_ns, stat.st_size)
+    return state
+
+
+def changed_files(old: FileState, new: FileState) -> List[str]:
+    """新增、删除或修改过的文件（已排序）"""
+    paths = old.keys() | new.keys()
+    return sorted(path for path in paths if old.get(path) != new.get(path))
+
+
+def watch(
+    analyzer,
+    project_path: str,
+    on_update: Callable[[CallGraph], None],
+    interval: float = 1.0,
+    debounce: float = 0.5,
+    exclude_dirs: Optional[List[str]] = None,
+    recursive: bool = True,
+    external: str = ExternalMode.DROP,
+    merge_names: bool = False,
+    implements: bool = False,
+    should_stop: Optional[Callable[[], bool]] = None,
+):
+    """
+    监视项目目录，源文件变化后重新分析并调用 on_update(新的调用图)
+
+    启动时先清空数据库完整分析一次并回调。之后每 interval 秒检查一次文件的
+    修改时间和大小（不依赖操作系统的文件通知，网络文件系统和容器挂载目录中
+    也能工作）；发现变化后等待文件在 debounce 秒内不再变化再分析，编辑器保存时
+    的多次连续写入只触发一次分析。文件变化后通过 analyzer.update() 增量更新，
+    只重新解析变化的文件和受它们影响的文件。每次分析后打印处理失败和
+    有语法错误的文件（见 print_errors()），其他进度输出不显示。
+
+    Args:
+        analyzer: CallGraphAnalyzer 或 CallGraphAnalyzerOptimized
+        project_path: 项目路径
+        on_update: 每次分析完成后的回调，参数为新的调用图
+        interval: 检查文件变化的间隔（秒）
+        debounce: 防抖时间（秒），文件在这段时间内没有再变化才开始分析
+        exclude_dirs: 排除的目录列表（默认: DEFAULT_EXCLUDE_DIRS）
+        recursive: 是否递归分析子目录
+        external: 回调的调用图中外部调用的处理方式，见 ExternalMode
+        merge_names: 回调的调用图中把不同包中的同名函数合并为一个节点
+        implements: 回调的调用图中添加 Go 的类型节点和 implements 边
+        should_stop: 每次检查前调用，返回 True 时停止监视（默认一直运行，
+            直到 KeyboardInterrupt）
+    """
+    if interval <= 0 or debounce < 0:
+        raise ValueError(f"无效的检查间隔: interval={interval}, debounce={debounce}")
+    if exclude_dirs is None:
+        exclude_dirs = DEFAULT_EXCLUDE_DIRS
+    project = Path(project_path).resolve()
+    include_tests = analyzer.options.include_tests
+
+    def snapshot() -> FileState:
+        return scan_files(project, exclude_dirs, recursive, include_tests)
+
+    def rebuild(changed: Optional[List[str]] = None):
+        # 分析器会打印每个阶段的详细进度，监视模式下不输出；
+        # 但处理失败和有语法错误的文件每次分析后都要报告，否则图中缺少的边无从察觉
+        with open(os.devnull, "w") as devnull, redirect_stdout(devnull):
+            if changed is None:
+                analyzer.db.clear_all()
+                analyzer.analyze_project(
+                    str(project), exclude_dirs=exclude_dirs, recursive=recursive
+                )
+            else:
+                analyzer.update(changed)
+        print_errors(analyzer.errors)
+        on_update(analyzer.load_graph(external, merge_names, implements))
+
+    state = snapshot()
+    rebuild()
+
+    while not (should_stop and should_stop()):
+        time.sleep(interval)
+        current = snapshot()
+        if current == state:
+            continue
+
+        # 防抖：等待文件不再变化
+        while True:
+            time.sleep(debounce)
+            latest = snapshot()
+            if latest == current:
+                break
+            current = latest
+
+        changed = changed_files(state, current)
+        state = current
+        print(f"检测到 {len(changed)} 个文件变化，重新分析...")
+        for file_path in changed:
+            print(f"  {os.path.relpath(file_path, project)}")
+        rebuild(changed)
@@ -0,0 +1,21 @@
+// alpha 包中的 User 类型
+package alpha
+
+import (
+	"fmt"
+)
+
+// User 与 beta.User 同名，方法 Greet 也同名
+type User struct {
+	Name string
+}
+
+// New 与 beta.New 同名
+func New(name string) *User {
+	return &User{Name: name}
+}
+
+// Greet 返回问候语
+func (u *User) Greet() string {
+	return fmt.Sprintf("Hello, %s", u.Name)
+}
@@ -0,0 +1,26 @@
+// beta 包中的 User 类型
+package beta
+
+import (
+	"strings"
+)
+
+// User 与 alpha.User 同名
+type User struct {
+	ID   int
+	Name string
+}
+
+// New 与 alpha.New 同名，但参数不同
+func New(id int) *User {
+	return &User{ID: id, Name: "guest"}
+}
+
+// Greet 与 alpha.User.Greet 同名，只有它调用 shout
+func (u *User) Greet() string {
+	return shout(u.Name)
+}
+
+func shout(s string) string {
+	return strings.ToUpper(s) + "!"
+}
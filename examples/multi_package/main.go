@@ -0,0 +1,16 @@
+// 两个包中有同名的函数和方法，调用图中应该是不同的节点
+package main
+
+import (
+	"fmt"
+
+	"example.com/multi/alpha"
+	"example.com/multi/beta"
+)
+
+func main() {
+	a := alpha.New("Alice") // -> example.com/multi/alpha.New
+	b := beta.New(1)        // -> example.com/multi/beta.New
+	fmt.Println(a.Greet())  // -> example.com/multi/alpha.User.Greet
+	fmt.Println(b.Greet())  // -> example.com/multi/beta.User.Greet
+}
@@ -0,0 +1,29 @@
+// 结构体嵌入示例：Admin 通过嵌入 *User 获得 Greet 方法
+package main
+
+import (
+	"fmt"
+)
+
+// Admin 管理员，嵌入 User
+type Admin struct {
+	*User
+	Level int
+}
+
+// Auditor 审计员，嵌入 Admin（两层嵌入）
+type Auditor struct {
+	Admin
+}
+
+// GetAge 覆盖了 User.GetAge（深度更浅的方法优先）
+func (a Admin) GetAge() int {
+	return a.User.GetAge() + a.Level
+}
+
+// welcomeAdmin 调用通过嵌入提升的方法
+func welcomeAdmin(admin Admin, auditor Auditor) {
+	fmt.Println(admin.Greet())    // -> User.Greet
+	fmt.Println(auditor.Greet())  // -> User.Greet（两层嵌入）
+	fmt.Println(auditor.GetAge()) // -> Admin.GetAge
+}
@@ -0,0 +1,59 @@
+// 调用函数的返回值：构建器的链式调用和返回函数的函数（中间件）
+package main
+
+import (
+	"fmt"
+)
+
+// Query 构建器，每个方法返回自身以便链式调用
+type Query struct {
+	table string
+	limit int
+}
+
+func newQuery() *Query {
+	return &Query{}
+}
+
+func (q *Query) From(table string) *Query {
+	q.table = table
+	return q
+}
+
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+func (q *Query) Run() string {
+	return fmt.Sprintf("SELECT * FROM %s LIMIT %d", q.table, q.limit)
+}
+
+// logging 返回一个包装了 next 的函数
+func logging(next func(string)) func(string) {
+	return func(path string) {
+		fmt.Println("request:", path)
+		next(path)
+	}
+}
+
+func serveIndex(path string) {
+	fmt.Println("index", path)
+}
+
+// defaultShape 返回接口类型
+func defaultShape() Shape {
+	return Square{Side: 1}
+}
+
+// chainedCalls 调用其他调用的返回值
+func chainedCalls() {
+	// 返回值的类型已知：解析到 Query.From、Query.Limit、Query.Run
+	fmt.Println(newQuery().From("users").Limit(10).Run())
+
+	// 返回值是接口：与接口变量的方法调用一样展开到 Circle.Area、Square.Area
+	fmt.Println(defaultShape().Area())
+
+	// 调用返回的函数：无法确定目标，记录为 unresolved（logging()）
+	logging(serveIndex)("/")
+}
@@ -0,0 +1,11 @@
+// 构建约束示例：configDir 在不同平台的文件中各有一个实现
+package main
+
+import (
+	"fmt"
+)
+
+// loadConfig 加载配置，调用的 configDir 由构建环境（--goos）决定
+func loadConfig() {
+	fmt.Println("config:", configDir())
+}
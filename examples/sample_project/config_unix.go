@@ -0,0 +1,12 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"os"
+)
+
+// configDir 类 Unix 系统的配置目录
+func configDir() string {
+	return os.Getenv("HOME") + "/.config"
+}
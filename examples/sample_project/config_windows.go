@@ -0,0 +1,11 @@
+// 文件名的 _windows 后缀本身就是构建约束，不需要 //go:build
+package main
+
+import (
+	"os"
+)
+
+// configDir Windows 的配置目录
+func configDir() string {
+	return os.Getenv("APPDATA")
+}
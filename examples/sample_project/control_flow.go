@@ -0,0 +1,89 @@
+// 控制流语句中的调用示例：嵌套在任何语句中的调用都归属于外层函数
+package main
+
+import (
+	"fmt"
+)
+
+func ready() bool {
+	return true
+}
+
+func pending() []int {
+	return []int{1, 2, 3}
+}
+
+func step(i int) int {
+	return i + 1
+}
+
+func bucket(i int) int {
+	return i % 3
+}
+
+func reset() {
+	fmt.Println("reset")
+}
+
+// controlFlow 在每种语句的各个位置调用函数
+func controlFlow(values chan int, done chan struct{}, v interface{}) {
+	// if 的初始化语句、条件和各个分支
+	if ok := ready(); ok {
+		step(1)
+	} else if ready() {
+		step(2)
+	} else {
+		reset()
+	}
+
+	// for 子句的初始化、条件和后置语句
+	for i := bucket(0); i < bucket(3); i += bucket(1) {
+		step(i)
+	}
+
+	// range 表达式和循环体
+	for _, item := range pending() {
+		step(item)
+	}
+
+	// switch 的表达式、case 表达式和 case 体
+	switch bucket(4) {
+	case bucket(5):
+		step(5)
+	default:
+		reset()
+	}
+
+	// type switch 的 case 体
+	switch x := v.(type) {
+	case int:
+		step(x)
+	case fmt.Stringer:
+		fmt.Println(x.String())
+	}
+
+	// 带标签的语句，select 的接收、发送和 case 体
+outer:
+	for {
+		select {
+		case n := <-values:
+			step(n)
+		case values <- bucket(6):
+			defer reset()
+		case <-done:
+			// select 中的匿名函数：step(7) 归属于 controlFlow$func1
+			go func() {
+				step(7)
+			}()
+			break outer
+		}
+	}
+
+	// 带标签的代码块
+retry:
+	{
+		if !ready() {
+			goto retry
+		}
+	}
+}
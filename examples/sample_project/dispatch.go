@@ -0,0 +1,37 @@
+// 包级函数变量示例
+package main
+
+import (
+	"fmt"
+)
+
+// 初始值是函数名，调用 defaultHandler() 解析到 add
+var defaultHandler = add
+
+// 初始值是另一个包级函数变量，同样解析到 add
+var fallbackHandler = defaultHandler
+
+// 保存在 map 中的函数，无法静态确定调用的是哪一个
+var operations = map[string]func(int, int) int{
+	"add":      add,
+	"multiply": multiply,
+}
+
+// 在 init 中被重新赋值，运行时指向哪个函数取决于条件
+var combine func(int, int) int = add
+
+var verbose bool
+
+func init() {
+	if verbose {
+		combine = multiply
+	}
+}
+
+// dispatch 通过包级函数变量调用
+func dispatch(op string, x, y int) int {
+	fmt.Println(defaultHandler(x, y))  // -> add
+	fmt.Println(fallbackHandler(x, y)) // -> add
+	fmt.Println(combine(x, y))         // -> combine（unresolved）
+	return operations[op](x, y)        // -> operations（unresolved）
+}
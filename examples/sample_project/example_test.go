@@ -0,0 +1,26 @@
+// 测试文件示例：只有使用 analyze --tests 时才会被分析
+package main
+
+import (
+	"testing"
+)
+
+// TestCalculate 测试 calculate（测试函数作为调用图的入口）
+func TestCalculate(t *testing.T) {
+	if got := calculate(2, 3); got != 11 {
+		t.Errorf("calculate(2, 3) = %d, want 11", got)
+	}
+}
+
+// BenchmarkGreet 基准测试
+func BenchmarkGreet(b *testing.B) {
+	user := &User{Name: "Bench"}
+	for i := 0; i < b.N; i++ {
+		user.Greet()
+	}
+}
+
+// newTestUser 测试辅助函数（不是测试入口）
+func newTestUser() *User {
+	return &User{Name: "Test", Age: 1}
+}
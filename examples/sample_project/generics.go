@@ -0,0 +1,60 @@
+// 泛型示例：泛型函数调用泛型函数，以及不同类型实参的实例化
+package main
+
+import (
+	"strconv"
+)
+
+// Stack 泛型栈
+type Stack[T any] struct {
+	items []T
+}
+
+// Push 入栈
+func (s *Stack[T]) Push(v T) {
+	s.items = append(s.items, v)
+}
+
+// Len 元素个数
+func (s *Stack[T]) Len() int {
+	return len(s.items)
+}
+
+// Map 对每个元素应用 f
+func Map[T, U any](xs []T, f func(T) U) []U {
+	out := make([]U, 0, len(xs))
+	for _, x := range xs {
+		out = append(out, f(x))
+	}
+	return out
+}
+
+// Filter 保留满足条件的元素
+func Filter[T any](xs []T, keep func(T) bool) []T {
+	var out []T
+	for _, x := range xs {
+		if keep(x) {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// MapFilter 泛型函数调用其他泛型函数
+func MapFilter[T, U any](xs []T, keep func(T) bool, f func(T) U) []U {
+	return Map(Filter(xs, keep), f)
+}
+
+// useGenerics 不同的实例化都指向同一个泛型函数节点
+func useGenerics() int {
+	nums := []int{1, 2, 3}
+	evens := Filter[int](nums, func(n int) bool { return n%2 == 0 })
+	strs := Map[int, string](evens, strconv.Itoa)
+	lengths := MapFilter(strs, func(s string) bool { return s != "" }, func(s string) int { return len(s) })
+
+	stack := &Stack[int]{}
+	for _, n := range lengths {
+		stack.Push(n)
+	}
+	return stack.Len()
+}
@@ -0,0 +1,38 @@
+// 同名方法示例：User 和 Robot 是无关的类型，都声明了 Greet
+// 接收者的类型可以推断时精确解析；无法推断时连到所有同名的方法，标记为 ambiguous
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Robot 与 User 没有关系，只是同样声明了 Greet
+type Robot struct {
+	Model string
+}
+
+func (r *Robot) Greet() string {
+	return "beep " + r.Model
+}
+
+// greetAll 在不同类型的变量上调用 Greet
+func greetAll(users []User, robots []*Robot) {
+	// 类型已知：分别解析到 User.Greet 和 Robot.Greet
+	u := &User{Name: "alice"}
+	r := &Robot{Model: "r2"}
+	fmt.Println(u.Greet(), r.Greet())
+
+	// range 变量和索引表达式的类型无法推断：
+	// 每处调用都连到 User.Greet 和 Robot.Greet（ambiguous）
+	for _, user := range users {
+		fmt.Println(user.Greet())
+	}
+	for _, robot := range robots {
+		fmt.Println(robot.Greet())
+	}
+	fmt.Println(users[0].Greet())
+
+	// 外部包中的值不会是项目中的类型：仍然是外部调用，不会连到 Report.Write
+	os.Stdout.Write([]byte("done\n"))
+}
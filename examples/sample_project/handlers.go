@@ -0,0 +1,32 @@
+// 方法值与方法表达式示例
+package main
+
+import (
+	"fmt"
+)
+
+// Greeter 问候接口
+type Greeter interface {
+	Greet() string
+}
+
+// registerHandlers 先把方法保存到变量中，再通过变量调用
+func registerHandlers(user *User, greeter Greeter) {
+	greet := user.Greet // 方法值，绑定了接收者 user
+	fmt.Println(greet()) // -> User.Greet
+
+	getAge := User.GetAge      // 方法表达式，接收者是第一个参数
+	fmt.Println(getAge(*user)) // -> User.GetAge
+
+	var compute func(int, int) int = calculate // 函数值
+	fmt.Println(compute(1, 2))                 // -> calculate
+
+	say := greeter.Greet // 接口的方法值
+	fmt.Println(say())   // -> User.Greet、Robot.Greet（interface）
+}
+
+// methodExpressions 直接调用方法表达式
+func methodExpressions(user *User) {
+	fmt.Println(User.GetAge(*user))  // -> User.GetAge
+	fmt.Println((*User).Greet(user)) // -> User.Greet
+}
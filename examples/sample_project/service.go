@@ -0,0 +1,56 @@
+// 结构体中函数类型字段的调用示例（依赖注入风格）
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Logger 通过函数字段输出日志
+type Logger struct {
+	Log func(string)
+}
+
+// Handler 是底层类型为函数的命名类型
+type Handler func(string) string
+
+// Service 在构造函数中注入回调
+type Service struct {
+	logger    Logger
+	onRequest Handler
+	transform func(string) string
+	hook      func()
+}
+
+func printLog(msg string) {
+	fmt.Println(msg)
+}
+
+func normalize(req string) string {
+	return strings.ToUpper(strings.TrimSpace(req))
+}
+
+// NewService 中给字段赋的都是已知的函数，调用可以解析到具体的函数
+func NewService() *Service {
+	s := &Service{
+		logger:    Logger{Log: printLog},
+		transform: normalize,
+	}
+	s.onRequest = s.handle // 方法值
+	return s
+}
+
+func (s *Service) handle(req string) string {
+	return s.transform(req) // -> normalize
+}
+
+// SetHook 的参数是调用方传入的任意函数，hook() 无法确定目标
+func (s *Service) SetHook(hook func()) {
+	s.hook = hook
+}
+
+func (s *Service) Serve(req string) {
+	s.logger.Log("serving " + req) // -> printLog
+	s.onRequest(req)               // -> Service.handle
+	s.hook()                       // -> Service.hook（unresolved）
+}
@@ -0,0 +1,65 @@
+// 示例Go项目：接口调用
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Shape 图形接口
+type Shape interface {
+	Area() float64
+	Name() string
+}
+
+// Circle 圆形
+type Circle struct {
+	Radius float64
+}
+
+// Area 圆的面积
+func (c *Circle) Area() float64 {
+	return 3.14 * c.Radius * c.Radius
+}
+
+// Name 圆的名称
+func (c *Circle) Name() string {
+	return "circle"
+}
+
+// Square 正方形
+type Square struct {
+	Side float64
+}
+
+// Area 正方形面积
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+// Name 正方形名称
+func (s Square) Name() string {
+	return "square"
+}
+
+// Report 报告输出
+type Report struct{}
+
+// Write 实现 io.Writer
+func (r *Report) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+// describe 通过接口调用方法，会解析到 Circle 和 Square 的实现
+func describe(s Shape) {
+	var w io.Writer = &Report{}
+	fmt.Fprintf(w, "%s: %.2f\n", s.Name(), s.Area())
+	w.Write([]byte("done\n"))
+}
+
+// printShapes 打印所有图形
+func printShapes() {
+	describe(&Circle{Radius: 1})
+	describe(Square{Side: 2})
+}
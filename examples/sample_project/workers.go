@@ -0,0 +1,34 @@
+// 匿名函数与 goroutine 示例
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// runWorkers 在 goroutine 中执行计算，逻辑都在匿名函数里
+func runWorkers(n int) int {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	total := 0
+
+	// 保存在变量中的匿名函数：record(...) 的调用解析到 runWorkers$func1
+	record := func(v int) {
+		mu.Lock()
+		total += v
+		mu.Unlock()
+	}
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		// runWorkers$func2
+		go func(x int) {
+			defer wg.Done()
+			record(calculate(x, x))
+		}(i)
+	}
+	wg.Wait()
+
+	fmt.Println("total:", total)
+	return total
+}
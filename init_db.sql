@@ -13,7 +13,10 @@ CREATE TABLE IF NOT EXISTS symbols(
     language TEXT,
     extras_json TEXT,
     code_excerpt TEXT,
-    is_exported INTEGER
+    is_exported INTEGER,
+    package TEXT,  -- 所属包（Go 为导入路径或包名），用于生成限定名
+    receiver TEXT,  -- 方法的接收者类型名（Go），普通函数为空
+    start_column INTEGER  -- 声明起始列（从 0 开始，与 call_site_column 相同）
 );
 
 -- 调用关系表：存储函数之间的调用关系
@@ -28,6 +31,7 @@ CREATE TABLE IF NOT EXISTS call_relations(
     call_site_line INTEGER,
     call_site_column INTEGER,
     language TEXT,
+    kind TEXT DEFAULT 'direct',  -- 调用类型：direct（直接调用）、interface（接口调用）等
     FOREIGN KEY (caller_id) REFERENCES symbols(id),
     FOREIGN KEY (callee_id) REFERENCES symbols(id)
 );
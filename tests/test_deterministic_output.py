@@ -0,0 +1,71 @@
+"""
+多次分析同一个项目，导出的结果必须逐字节相同（便于把生成的图提交到版本库）
+
+每次分析在单独的进程中进行，并使用不同的 PYTHONHASHSEED，
+集合和字典的遍历顺序不同时也能发现输出顺序的变化
+"""
+
+import json
+import os
+import subprocess
+import sys
+import tempfile
+import unittest
+from pathlib import Path
+
+ROOT = Path(__file__).resolve().parent.parent
+PROJECT = ROOT / "examples" / "sample_project"
+
+# 在子进程中分析项目，以 JSON 输出每种格式的导出结果
+ANALYZE_AND_EXPORT = """
+import contextlib, io, json, sys
+from call_graph.analyzer import CallGraphAnalyzer
+
+db_path, project, *formats = sys.argv[1:]
+with contextlib.redirect_stdout(io.StringIO()):
+    with CallGraphAnalyzer(db_path) as analyzer:
+        analyzer.analyze_project(project)
+        outputs = {name: analyzer.export_graph(name) for name in formats}
+print(json.dumps(outputs))
+"""
+
+# SVG 依赖是否安装了 Graphviz，不在这里比较
+FORMATS = ["dot", "json", "graphml", "mermaid", "csv"]
+
+
+def analyze_and_export(directory: str, hash_seed: str) -> dict:
+    env = dict(os.environ, PYTHONHASHSEED=hash_seed)
+    env["PYTHONPATH"] = os.pathsep.join(
+        filter(None, [str(ROOT), env.get("PYTHONPATH")])
+    )
+    db_path = str(Path(directory) / f"run-{hash_seed}.db")
+    result = subprocess.run(
+        [sys.executable, "-c", ANALYZE_AND_EXPORT, db_path, str(PROJECT), *FORMATS],
+        cwd=ROOT,
+        env=env,
+        capture_output=True,
+        text=True,
+    )
+    if result.returncode != 0:
+        raise RuntimeError(f"分析失败:\n{result.stderr}")
+    return json.loads(result.stdout)
+
+
+class DeterministicOutputTest(unittest.TestCase):
+    @classmethod
+    def setUpClass(cls):
+        with tempfile.TemporaryDirectory() as directory:
+            cls.first = analyze_and_export(directory, "1")
+            cls.second = analyze_and_export(directory, "2")
+
+    def test_outputs_are_byte_identical(self):
+        for name in FORMATS:
+            with self.subTest(format=name):
+                self.assertTrue(self.first[name])
+                self.assertEqual(
+                    self.first[name].encode("utf-8"), self.second[name].encode("utf-8")
+                )
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,86 @@
+"""
+不同包中的同名函数：默认按限定名区分为不同的节点，merge_names 时合并为一个节点
+"""
+
+import contextlib
+import io
+import json
+import tempfile
+import unittest
+from pathlib import Path
+
+from call_graph.analyzer import CallGraphAnalyzer
+
+PROJECT = Path(__file__).resolve().parent.parent / "examples" / "multi_package"
+
+
+class MultiPackageTest(unittest.TestCase):
+    @classmethod
+    def setUpClass(cls):
+        cls.tmp = tempfile.TemporaryDirectory()
+        cls.analyzer = CallGraphAnalyzer(str(Path(cls.tmp.name) / "call_graph.db"))
+        with contextlib.redirect_stdout(io.StringIO()):
+            cls.analyzer.analyze_project(str(PROJECT))
+
+    @classmethod
+    def tearDownClass(cls):
+        cls.analyzer.close()
+        cls.tmp.cleanup()
+
+    def callee_names(self, graph, caller: str):
+        (main,) = graph.find(caller)
+        return sorted(
+            graph.nodes[edge.callee].qualified_name
+            for edge in graph.edges.values()
+            if edge.caller == main.id
+        )
+
+    def test_same_names_are_distinct_by_default(self):
+        graph = self.analyzer.load_graph()
+
+        self.assertEqual(
+            [node.qualified_name for node in graph.find("User.Greet")],
+            [
+                "example.com/multi/alpha.User.Greet",
+                "example.com/multi/beta.User.Greet",
+            ],
+        )
+        self.assertEqual(
+            self.callee_names(graph, "main"),
+            [
+                "example.com/multi/alpha.New",
+                "example.com/multi/alpha.User.Greet",
+                "example.com/multi/beta.New",
+                "example.com/multi/beta.User.Greet",
+            ],
+        )
+
+    def test_merge_names(self):
+        graph = self.analyzer.load_graph(merge_names=True)
+
+        greet = graph.find("User.Greet")
+        self.assertEqual(len(greet), 1)
+        self.assertEqual(len(graph.find("New")), 1)
+        self.assertEqual(
+            self.callee_names(graph, "main"),
+            sorted([greet[0].qualified_name, graph.find("New")[0].qualified_name]),
+        )
+
+    def test_export_merge_names(self):
+        with contextlib.redirect_stdout(io.StringIO()):
+            distinct = json.loads(self.analyzer.export_graph("json"))
+            merged = json.loads(self.analyzer.export_graph("json", merge_names=True))
+
+        def greet_nodes(exported):
+            return [n for n in exported["nodes"] if n["name"] == "User.Greet"]
+
+        self.assertEqual(
+            sorted(n["package"] for n in greet_nodes(distinct)),
+            ["example.com/multi/alpha", "example.com/multi/beta"],
+        )
+        self.assertEqual(len(greet_nodes(merged)), 1)
+        self.assertEqual(len(merged["edges"]), len(distinct["edges"]) - 2)
+
+
+if __name__ == "__main__":
+    unittest.main()